@@ -0,0 +1,238 @@
+package gocloc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// cacheVersion changes with the counting of the lines, discarding the caches of older versions.
+const cacheVersion = 2
+
+// CacheFileName is the name of the cache file in ClocOptions.CacheDir.
+const CacheFileName = "gocloc.cache"
+
+// cacheEntry is the counts of a file together with what they were counted from.
+type cacheEntry struct {
+	Size    int64
+	ModTime int64
+	Hash    string
+	// LangVersion is the fingerprint of the language definition and the options the file was counted with.
+	LangVersion string
+	File        ClocFile
+}
+
+// cacheData is the content of the cache file.
+type cacheData struct {
+	Version int
+	Entries map[string]*cacheEntry
+}
+
+// fileCache keeps the counts of the files between runs in a directory.
+type fileCache struct {
+	path string
+
+	mu    sync.Mutex
+	data  cacheData
+	dirty bool
+	// seen are the keys of the files of the run, the other entries under its roots are pruned by save.
+	seen map[string]struct{}
+}
+
+// openFileCache reads the cache of dir. A missing, broken or older cache, or reset, starts empty.
+func openFileCache(dir string, reset bool) *fileCache {
+	c := &fileCache{
+		path: filepath.Join(dir, CacheFileName),
+		data: cacheData{Version: cacheVersion, Entries: make(map[string]*cacheEntry)},
+		seen: make(map[string]struct{}),
+	}
+	if reset {
+		c.dirty = true
+		return c
+	}
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+	var data cacheData
+	if err := gob.NewDecoder(f).Decode(&data); err != nil || data.Version != cacheVersion || data.Entries == nil {
+		c.dirty = true
+		return c
+	}
+	c.data = data
+	return c
+}
+
+// save writes the cache when it changed, replacing the cache file at once. The entries of the
+// files under the keys of roots not seen by the run are dropped, the run must have seen every
+// file under roots. The entries of the other paths are kept for the runs counting them.
+func (c *fileCache) save(roots []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.data.Entries {
+		if _, ok := c.seen[key]; ok {
+			continue
+		}
+		for _, root := range roots {
+			if underCacheRoot(key, root) {
+				delete(c.data.Entries, key)
+				c.dirty = true
+				break
+			}
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, CacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := gob.NewEncoder(f).Encode(&c.data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// load returns the entry of key, marking the file as seen by the run.
+func (c *fileCache) load(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[key] = struct{}{}
+	return c.data.Entries[key]
+}
+
+func (c *fileCache) store(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Entries[key] = entry
+	c.dirty = true
+}
+
+// cacheKey returns the key of filename, the absolute path on the OS file system. The keys of
+// the other file systems start with their name, and the tree of the revision for GitRevisionFs.
+func cacheKey(fsys afero.Fs, filename string) string {
+	path := filepath.Clean(filename)
+	if gfs, ok := fsys.(*gitRevisionFs); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(gfs.dir, path)
+		}
+		return gfs.Name() + ":" + gfs.tree + ":" + path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if _, ok := fsys.(*afero.OsFs); ok {
+		return path
+	}
+	return fsys.Name() + ":" + path
+}
+
+// underCacheRoot reports whether key is the key of root or of a file under it.
+func underCacheRoot(key, root string) bool {
+	return key == root || strings.HasPrefix(key, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// languageVersion returns the fingerprint of the definition of language and the options
+// that change the counts of a file.
+func languageVersion(language *Language, opts *ClocOptions) string {
+	declaration := ""
+	if language.docs.Declaration != nil {
+		declaration = language.docs.Declaration.String()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%q\x00%q\x00%v\x00%+v\x00%v\x00%v\x00%q\x00%q\x00%q\x00%v",
+		cacheVersion, language.Name, language.lineComments, language.multiLines, language.nestable,
+		language.stringLiterals, language.guessedLiterals, language.lineStartComments,
+		language.docs.LineComments, language.docs.MultiLines, declaration, opts.Embedded)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// analyzeFile is like analyzeFile, but returns the counts of the cache when the file did not change.
+// A file on the OS file system with the size and the modification time of the cache is not read,
+// the other files are compared by the hash of their content.
+func (c *fileCache) analyzeFile(ctx context.Context, filename string, language *Language, opts *ClocOptions) (*ClocFile, error) {
+	if err := ctx.Err(); err != nil {
+		return &ClocFile{Name: filename}, err
+	}
+
+	fsys := opts.fs()
+	info, err := fsys.Stat(filename)
+	if err != nil {
+		return analyzeFile(ctx, filename, language, opts)
+	}
+	key := cacheKey(fsys, filename)
+	langVersion := languageVersion(language, opts)
+	entry := c.load(key)
+	if entry != nil && (entry.LangVersion != langVersion || entry.Size != info.Size()) {
+		entry = nil
+	}
+	if _, ok := fsys.(*afero.OsFs); ok && entry != nil && entry.ModTime == info.ModTime().UnixNano() {
+		if opts.Debug {
+			fmt.Printf("[cache=%v] hit\n", filename)
+		}
+		return entry.clocFile(filename), nil
+	}
+
+	content, err := afero.ReadFile(fsys, filename)
+	if err != nil {
+		// ignore error
+		return &ClocFile{Name: filename}, nil
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if entry != nil && entry.Hash == hash {
+		if opts.Debug {
+			fmt.Printf("[cache=%v] hit by content\n", filename)
+		}
+		updated := *entry
+		updated.ModTime = info.ModTime().UnixNano()
+		c.store(key, &updated)
+		return entry.clocFile(filename), nil
+	}
+
+	clocFile, err := AnalyzeReaderContext(ctx, filename, language, bytes.NewReader(content), opts)
+	if err != nil {
+		return clocFile, err
+	}
+	c.store(key, &cacheEntry{
+		Size:        info.Size(),
+		ModTime:     info.ModTime().UnixNano(),
+		Hash:        hash,
+		LangVersion: langVersion,
+		File:        *clocFile,
+	})
+	return clocFile, nil
+}
+
+// clocFile returns a copy of the counts of the entry for filename.
+func (e *cacheEntry) clocFile(filename string) *ClocFile {
+	clocFile := e.File
+	clocFile.Name = filename
+	clocFile.Embedded = append(ClocFiles(nil), e.File.Embedded...)
+	return &clocFile
+}
@@ -0,0 +1,228 @@
+package gocloc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeWithCacheMatchesUncached(t *testing.T) {
+	dir := writeTestTree(t)
+	writeTestFiles(t, dir, map[string]string{
+		"index.html": "<html>\n<script>\n// comment\nvar x = 1;\n</script>\n</html>\n",
+	})
+	cacheDir := t.TempDir()
+
+	opts := NewClocOptions()
+	opts.Embedded = true
+	uncached, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	for _, run := range []string{"cold", "warm", "warm parallel"} {
+		cachedOpts := NewClocOptions()
+		cachedOpts.Embedded = true
+		cachedOpts.CacheDir = cacheDir
+		if run == "warm parallel" {
+			cachedOpts.Jobs = 4
+		}
+		cached, err := NewProcessor(NewDefinedLanguages(), cachedOpts).Analyze([]string{dir})
+		if err != nil {
+			t.Fatalf("invalid logic. run=%v err=[%v]", run, err)
+		}
+		if !reflect.DeepEqual(uncached.Total, cached.Total) {
+			t.Errorf("invalid logic. run=%v total uncached=%v cached=%v", run, uncached.Total, cached.Total)
+		}
+		if !reflect.DeepEqual(uncached.Files, cached.Files) {
+			t.Errorf("invalid logic. run=%v files differ", run)
+		}
+		if !reflect.DeepEqual(uncached.Languages, cached.Languages) {
+			t.Errorf("invalid logic. run=%v languages differ", run)
+		}
+	}
+
+	cache := openFileCache(cacheDir, false)
+	if n := len(cache.data.Entries); n != len(uncached.Files) {
+		t.Errorf("invalid logic. entries=%v files=%v", n, len(uncached.Files))
+	}
+}
+
+func TestAnalyzeWithCacheInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {\n}\n"})
+	cacheDir := t.TempDir()
+
+	analyze := func(reset bool) *ClocFile {
+		opts := NewClocOptions()
+		opts.CacheDir = cacheDir
+		opts.ResetCache = reset
+		result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+		if err != nil {
+			t.Fatalf("invalid logic. err=[%v]", err)
+		}
+		return result.Files[path]
+	}
+	if cf := analyze(false); cf.Code != 3 {
+		t.Fatalf("invalid logic. code=%v", cf.Code)
+	}
+
+	// a tampered entry shows when the cache is used
+	cache := openFileCache(cacheDir, false)
+	for _, entry := range cache.data.Entries {
+		entry.File.Code = 100
+	}
+	cache.dirty = true
+	if err := cache.save(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cf := analyze(false); cf.Code != 100 {
+		t.Errorf("invalid logic. unchanged file code=%v", cf.Code)
+	}
+
+	// a new modification time with the same content is found by the hash
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if cf := analyze(false); cf.Code != 100 {
+		t.Errorf("invalid logic. touched file code=%v", cf.Code)
+	}
+
+	if cf := analyze(true); cf.Code != 3 {
+		t.Errorf("invalid logic. reset cache code=%v", cf.Code)
+	}
+
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cf := analyze(false); cf.Code != 4 {
+		t.Errorf("invalid logic. changed file code=%v", cf.Code)
+	}
+}
+
+func TestLanguageVersion(t *testing.T) {
+	opts := NewClocOptions()
+	golang := NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}})
+	other := NewLanguage("Go", []string{"#"}, [][]string{{"/*", "*/"}})
+	if languageVersion(golang, opts) == languageVersion(other, opts) {
+		t.Errorf("invalid logic. changed definition has the same version")
+	}
+	rules := map[string]*Language{
+		"plain":   NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}),
+		"strings": NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(goStringLiterals...),
+		"nested":  NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+		"docs":    NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}).WithDocComments(javadocComments),
+		"decl":    NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}).WithDocComments(DocComments{Declaration: goExportedDeclaration}),
+	}
+	versions := make(map[string]string)
+	for name, language := range rules {
+		version := languageVersion(language, opts)
+		if other, ok := versions[version]; ok {
+			t.Errorf("invalid logic. rules %v and %v have the same version", name, other)
+		}
+		versions[version] = name
+	}
+
+	embedded := NewClocOptions()
+	embedded.Embedded = true
+	if languageVersion(golang, opts) == languageVersion(golang, embedded) {
+		t.Errorf("invalid logic. changed options have the same version")
+	}
+}
+
+func TestAnalyzeWithCachePrunesUnseenFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go": "package main\n",
+		"util.py": "x = 1\n",
+	})
+	cacheDir := t.TempDir()
+
+	analyze := func() {
+		opts := NewClocOptions()
+		opts.CacheDir = cacheDir
+		if _, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir}); err != nil {
+			t.Fatalf("invalid logic. err=[%v]", err)
+		}
+	}
+	analyze()
+	if n := len(openFileCache(cacheDir, false).data.Entries); n != 2 {
+		t.Fatalf("invalid logic. entries=%v", n)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "util.py")); err != nil {
+		t.Fatal(err)
+	}
+	analyze()
+	entries := openFileCache(cacheDir, false).data.Entries
+	if len(entries) != 1 {
+		t.Errorf("invalid logic. entries=%v", len(entries))
+	}
+	if _, ok := entries[filepath.Join(dir, "main.go")]; !ok {
+		t.Errorf("invalid logic. entries=%v", entries)
+	}
+}
+
+func TestAnalyzeWithCacheSaveError(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n"})
+
+	var cacheErr error
+	opts := NewClocOptions()
+	// the cache directory cannot be made under a file
+	opts.CacheDir = filepath.Join(dir, "main.go", "cache")
+	opts.OnCacheError = func(err error) {
+		cacheErr = err
+	}
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+	if err != nil || result.Total.Code != 1 {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if cacheErr == nil {
+		t.Errorf("invalid logic. the error of saving the cache is not reported")
+	}
+}
+
+func TestAnalyzeWithCacheKeepsOtherRoots(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a/main.go":  "package main\n",
+		"b/util.py":  "x = 1\n",
+		"ab/util.rb": "y = 2\n",
+	})
+	cacheDir := t.TempDir()
+
+	analyze := func(paths ...string) {
+		opts := NewClocOptions()
+		opts.CacheDir = cacheDir
+		if _, err := NewProcessor(NewDefinedLanguages(), opts).Analyze(paths); err != nil {
+			t.Fatalf("invalid logic. err=[%v]", err)
+		}
+	}
+	analyze(dir)
+	// a run of a/ prunes nothing of b/ and ab/
+	analyze(filepath.Join(dir, "a"))
+	entries := openFileCache(cacheDir, false).data.Entries
+	if len(entries) != 3 {
+		t.Errorf("invalid logic. entries=%v", entries)
+	}
+}
+
+func TestCacheKeyOfGitRevision(t *testing.T) {
+	v1 := &gitRevisionFs{tree: strings.Repeat("1", 40), dir: "/repo"}
+	v2 := &gitRevisionFs{tree: strings.Repeat("2", 40), dir: "/repo"}
+	if cacheKey(v1, "main.go") == cacheKey(v2, "main.go") {
+		t.Errorf("invalid logic. the revisions have the same key %v", cacheKey(v1, "main.go"))
+	}
+	if !underCacheRoot(cacheKey(v1, "src/main.go"), cacheKey(v1, "src")) {
+		t.Errorf("invalid logic. %v is not under %v", cacheKey(v1, "src/main.go"), cacheKey(v1, "src"))
+	}
+	if underCacheRoot(cacheKey(v2, "src/main.go"), cacheKey(v1, "src")) {
+		t.Errorf("invalid logic. %v is under %v", cacheKey(v2, "src/main.go"), cacheKey(v1, "src"))
+	}
+}
@@ -1,24 +1,86 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/hhatto/gocloc"
 	flags "github.com/jessevdk/go-flags"
+	"os"
+	"regexp"
+	"runtime"
 	"sort"
+	"strings"
 )
 
+// Version is version string for gocloc command
+var Version string
+
+// GitCommit is git commit hash string for gocloc command
+var GitCommit string
+
+// OutputTypeDefault is cloc's text output format for --output-type option
+const OutputTypeDefault string = "default"
+
+// OutputTypeClocXML is Cloc's XML output format for --output-type option
+const OutputTypeClocXML string = "cloc-xml"
+
+// OutputTypeSloccount is Sloccount output format for --output-type option
+const OutputTypeSloccount string = "sloccount"
+
+// OutputTypeJSON is JSON output format for --output-type option
+const OutputTypeJSON string = "json"
+
+const fileHeader string = "File"
 const languageHeader string = "Language"
 const commonHeader string = "files          blank        comment           code"
+const mixedHeader string = "          mixed"
+const docCommentHeader string = "    doc comment"
+const embeddedRowPrefix string = " |- "
+const duplicatesHeader string = "Duplicates"
 const defaultOutputSeparator string = "-------------------------------------------------------------------------" +
 	"-------------------------------------------------------------------------" +
 	"-------------------------------------------------------------------------"
 
 var rowLen = 79
 
-// It is necessary to use  that follows go-flags.
+// CmdOptions is gocloc command options.
+// It is necessary to use notation that follows go-flags.
 type CmdOptions struct {
-	Byfile   bool   `long:"by-file" description:"report results for every encountered source file"`
-	MatchDir string `long:"match-d" description:"include dir name (regex)"`
+	Byfile         bool   `long:"by-file" description:"report results for every encountered source file"`
+	SortTag        string `long:"sort" default:"code" description:"sort based on a certain column [values: name,files,blank,comment,code]"`
+	OutputType     string `long:"output-type" default:"default" description:"output type [values: default,cloc-xml,sloccount,json]"`
+	ExcludeExt     string `long:"exclude-ext" description:"exclude file name extensions (separated commas)"`
+	IncludeLang    string `long:"include-lang" description:"include language name (separated commas)"`
+	Match          string `long:"match" description:"include file name (regex)"`
+	NotMatch       string `long:"not-match" description:"exclude file name (regex)"`
+	MatchDir       string `long:"match-d" description:"include dir name (regex)"`
+	NotMatchDir    string `long:"not-match-d" description:"exclude dir name (regex)"`
+	VCSDirs        string `long:"vcs-dirs" description:"metadata directories of version control systems that are skipped (separated commas), replacing the default list"`
+	ExcludePreset  string `long:"exclude-preset" description:"exclude the directories of presets (separated commas), see --list-presets"`
+	ReadLangDef    string `long:"read-lang-def" description:"load language definitions from file (JSON, YAML or cloc format), built-in definitions take precedence"`
+	ForceLangDef   string `long:"force-lang-def" description:"load language definitions from file (JSON, YAML or cloc format), overriding built-in definitions"`
+	Debug          bool   `long:"debug" description:"dump debug log for developer"`
+	SkipDuplicated bool   `long:"skip-duplicated" description:"skip duplicated files"`
+	ReportDups     bool   `long:"report-duplicates" description:"report the duplicated files and their lines (default and json output types)"`
+	NoIgnore       bool   `long:"no-ignore" description:"do not respect .gitignore, .git/info/exclude, the global excludes file and .gocloc-ignore"`
+	Jobs           int    `long:"jobs" description:"number of files analyzed in parallel (default: number of CPUs)"`
+	Enry           bool   `long:"enry" description:"detect languages with go-enry when the extension is unknown or ambiguous"`
+	Mixed          bool   `long:"mixed" description:"report code lines with a trailing comment in the mixed column"`
+	DocComments    bool   `long:"doc-comments" description:"report documentation comment lines in the doc comment column"`
+	Embedded       bool   `long:"embedded" description:"report the code of other languages in HTML, Markdown, Vue and Svelte files as sub-rows"`
+	SkipBinary     bool   `long:"skip-binary" description:"skip binary files"`
+	SkipGenerated  bool   `long:"skip-generated" description:"skip generated files (\"Code generated ... DO NOT EDIT.\" headers and lockfiles)"`
+	SkipMinified   bool   `long:"skip-minified" description:"skip minified files"`
+	SkipVendored   bool   `long:"skip-vendored" description:"skip vendored files"`
+	GitRev         string `long:"git-rev" description:"count the files of a commit, tag or branch of the git repository of the paths, without checking it out; HEAD~N, REV^N and REV^{} are accepted (OLD..NEW with --diff)"`
+	Cache          string `long:"cache" description:"keep the counts of the files in this directory, and count only the changed files on the next runs"`
+	ResetCache     bool   `long:"reset-cache" description:"discard the cache of --cache before counting"`
+	Diff           bool   `long:"diff" description:"report the same, modified, added and removed lines between two paths, or between the revisions of --git-rev=OLD..NEW"`
+	CPD            bool   `long:"cpd" description:"report the duplicated blocks of code lines across the files (copy/paste detector) in JSON"`
+	CPDMinLines    int    `long:"cpd-min-lines" description:"minimum number of code lines of a duplicated block with --cpd (default: 6)"`
+	ShowLang       bool   `long:"show-lang" description:"print about all languages and extensions"`
+	ListPresets    bool   `long:"list-presets" description:"print the exclusion presets and their directories"`
+	ShowVersion    bool   `long:"version" description:"print version info"`
 }
 
 type outputBuilder struct {
@@ -33,27 +95,155 @@ func newOutputBuilder(result *gocloc.Result, opts *CmdOptions) *outputBuilder {
 	}
 }
 
+// pathColumnLen returns the width of the file name column for --by-file.
+func (o *outputBuilder) pathColumnLen() int {
+	l := o.result.MaxPathLength
+	if l < len("TOTAL") {
+		l = len("TOTAL")
+	}
+	for _, file := range o.result.Files {
+		for _, e := range file.Embedded {
+			if n := len(embeddedRowPrefix + e.Lang); l < n {
+				l = n
+			}
+		}
+	}
+	return l
+}
+
+// columnsHeader returns the header of the count columns.
+func (o *outputBuilder) columnsHeader() string {
+	header := commonHeader
+	if o.opts.Mixed {
+		header += mixedHeader
+	}
+	if o.opts.DocComments {
+		header += docCommentHeader
+	}
+	return header
+}
+
+// endRow terminates a row of the default output, adding the columns of --mixed and --doc-comments.
+func (o *outputBuilder) endRow(mixed, docComments int32) {
+	if o.opts.Mixed {
+		fmt.Printf(" %14v", mixed)
+	}
+	if o.opts.DocComments {
+		fmt.Printf(" %14v", docComments)
+	}
+	fmt.Println()
+}
+
+// writeEmbeddedLanguages writes the sub-rows of the languages embedded in language.
+func (o *outputBuilder) writeEmbeddedLanguages(language *gocloc.Language) {
+	names := make([]string, 0, len(language.Embedded))
+	for name := range language.Embedded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := language.Embedded[name]
+		fmt.Printf("%-27v %6v %14v %14v %14v",
+			embeddedRowPrefix+child.Name, len(child.Files), child.Blanks, child.Comments, child.Code)
+		o.endRow(child.Mixed, child.DocComments)
+	}
+}
+
 func (o *outputBuilder) WriteHeader() {
-	maxPathLen := o.result.MaxPathLength
 	headerLen := 28
 	header := languageHeader
-	rowLen = maxPathLen + len(commonHeader) + 2
-	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
-	fmt.Printf("%-[2]*[1]s %[3]s\n", header, headerLen, commonHeader)
-	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	columns := o.columnsHeader()
+	rowLen += len(columns) - len(commonHeader)
+	if o.opts.Byfile {
+		maxPathLen := o.pathColumnLen()
+		headerLen = maxPathLen + 1
+		rowLen = maxPathLen + len(columns) + 2
+		header = fileHeader
+	}
+	if o.opts.OutputType == OutputTypeDefault {
+		fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+		fmt.Printf("%-[2]*[1]s %[3]s\n", header, headerLen, columns)
+		fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	}
 }
 
 func (o *outputBuilder) WriteFooter() {
 	total := o.result.Total
-	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
-	fmt.Printf("%-27v %6v %14v %14v %14v\n",
-		"TOTAL", total.Total, total.Blanks, total.Comments, total.Code)
-	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	if o.opts.OutputType == OutputTypeDefault {
+		fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+		if o.opts.Byfile {
+			fmt.Printf("%-[1]*[2]v %6[3]v %14[4]v %14[5]v %14[6]v",
+				o.pathColumnLen(), "TOTAL", total.Total, total.Blanks, total.Comments, total.Code)
+		} else {
+			fmt.Printf("%-27v %6v %14v %14v %14v",
+				"TOTAL", total.Total, total.Blanks, total.Comments, total.Code)
+		}
+		o.endRow(total.Mixed, total.DocComments)
+		fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	}
+}
+
+func (o *outputBuilder) writeResultWithByFile() {
+	total := o.result.Total
+	maxPathLen := o.pathColumnLen()
+
+	var sortedFiles gocloc.ClocFiles
+	for _, file := range o.result.Files {
+		sortedFiles = append(sortedFiles, *file)
+	}
+	sortFiles(sortedFiles, o.opts.SortTag)
+
+	switch o.opts.OutputType {
+	case OutputTypeClocXML:
+		xmlResult := gocloc.NewXMLFilesResultFromCloc(total, sortedFiles)
+		xmlResult.Encode()
+	case OutputTypeSloccount:
+		for _, file := range sortedFiles {
+			p := ""
+			if strings.HasPrefix(file.Name, "./") || string(file.Name[0]) == "/" {
+				splitPaths := strings.Split(file.Name, string(os.PathSeparator))
+				if len(splitPaths) >= 3 {
+					p = splitPaths[1]
+				}
+			}
+			fmt.Printf("%v\t%v\t%v\t%v\n",
+				file.Code, file.Lang, p, file.Name)
+		}
+	case OutputTypeJSON:
+		jsonResult := gocloc.NewJSONFilesResultFromCloc(total, sortedFiles)
+		if o.opts.ReportDups {
+			jsonResult.Duplicates = gocloc.NewJSONDuplicates(o.result)
+		}
+		buf, err := json.Marshal(jsonResult)
+		if err != nil {
+			fmt.Println(err)
+			panic("json marshal error")
+		}
+		os.Stdout.Write(buf)
+	default:
+		for _, file := range sortedFiles {
+			fmt.Printf("%-[1]*[2]s %21[3]v %14[4]v %14[5]v",
+				maxPathLen, file.Name, file.Blanks, file.Comments, file.Code)
+			o.endRow(file.Mixed, file.DocComments)
+			for _, e := range file.Embedded {
+				fmt.Printf("%-[1]*[2]s %21[3]v %14[4]v %14[5]v",
+					maxPathLen, embeddedRowPrefix+e.Lang, e.Blanks, e.Comments, e.Code)
+				o.endRow(e.Mixed, e.DocComments)
+			}
+		}
+	}
 }
 
 func (o *outputBuilder) WriteResult() {
 	o.WriteHeader()
 
+	if o.opts.Byfile {
+		o.writeResultWithByFile()
+		o.WriteFooter()
+		o.writeDuplicates()
+		return
+	}
+
 	clocLangs := o.result.Languages
 
 	var sortedLanguages gocloc.Languages
@@ -62,14 +252,366 @@ func (o *outputBuilder) WriteResult() {
 			sortedLanguages = append(sortedLanguages, *language)
 		}
 	}
-	sort.Sort(sortedLanguages)
+	sortLanguages(sortedLanguages, o.opts.SortTag)
 
-	for _, language := range sortedLanguages {
-		fmt.Printf("%-27v %6v %14v %14v %14v\n",
-			language.Name, len(language.Files), language.Blanks, language.Comments, language.Code)
+	switch o.opts.OutputType {
+	case OutputTypeClocXML:
+		xmlResult := gocloc.NewXMLResultFromCloc(o.result.Total, sortedLanguages, gocloc.XMLResultWithLangs)
+		xmlResult.Encode()
+	case OutputTypeJSON:
+		jsonResult := gocloc.NewJSONLanguagesResultFromCloc(o.result.Total, sortedLanguages)
+		if o.opts.ReportDups {
+			jsonResult.Duplicates = gocloc.NewJSONDuplicates(o.result)
+		}
+		buf, err := json.Marshal(jsonResult)
+		if err != nil {
+			fmt.Println(err)
+			panic("json marshal error")
+		}
+		os.Stdout.Write(buf)
+	default:
+		for _, language := range sortedLanguages {
+			fmt.Printf("%-27v %6v %14v %14v %14v",
+				language.Name, len(language.Files), language.Blanks, language.Comments, language.Code)
+			o.endRow(language.Mixed, language.DocComments)
+			o.writeEmbeddedLanguages(&language)
+		}
 	}
 
 	o.WriteFooter()
+	o.writeDuplicates()
+}
+
+// writeDuplicates writes the lines of the duplicated files by language, and each counted file
+// with its copies, for --report-duplicates.
+func (o *outputBuilder) writeDuplicates() {
+	if !o.opts.ReportDups || o.opts.OutputType != OutputTypeDefault {
+		return
+	}
+	duplicates := o.result.Duplicates
+	sep := defaultOutputSeparator[:79]
+
+	fmt.Println()
+	fmt.Println(sep)
+	fmt.Printf("%-28v %v\n", duplicatesHeader, commonHeader)
+	fmt.Println(sep)
+	for _, language := range duplicates.SortedLanguages() {
+		fmt.Printf("%-27v %6v %14v %14v %14v\n",
+			language.Name, language.FilesCount, language.Blanks, language.Comments, language.Code)
+	}
+	fmt.Println(sep)
+	total := duplicates.Total
+	fmt.Printf("%-27v %6v %14v %14v %14v\n",
+		"TOTAL", total.FilesCount, total.Blanks, total.Comments, total.Code)
+	fmt.Println(sep)
+	for _, original := range duplicates.SortedOriginals() {
+		fmt.Println(original)
+		for _, file := range duplicates.Files[original] {
+			fmt.Println(embeddedRowPrefix + file)
+		}
+	}
+}
+
+// sortLanguages sorts languages by the column of --sort, ties are ordered by name.
+func sortLanguages(languages gocloc.Languages, sortTag string) {
+	switch sortTag {
+	case "name":
+		sort.Slice(languages, func(i, j int) bool {
+			return languages[i].Name < languages[j].Name
+		})
+	case "files":
+		sort.Slice(languages, func(i, j int) bool {
+			if len(languages[i].Files) == len(languages[j].Files) {
+				return languages[i].Name < languages[j].Name
+			}
+			return len(languages[i].Files) > len(languages[j].Files)
+		})
+	case "blank":
+		sort.Slice(languages, func(i, j int) bool {
+			if languages[i].Blanks == languages[j].Blanks {
+				return languages[i].Name < languages[j].Name
+			}
+			return languages[i].Blanks > languages[j].Blanks
+		})
+	case "comment":
+		sort.Slice(languages, func(i, j int) bool {
+			if languages[i].Comments == languages[j].Comments {
+				return languages[i].Name < languages[j].Name
+			}
+			return languages[i].Comments > languages[j].Comments
+		})
+	default:
+		sort.Sort(languages)
+	}
+}
+
+// sortFiles sorts files by the column of --sort, "files" falls back to the code column.
+func sortFiles(files gocloc.ClocFiles, sortTag string) {
+	switch sortTag {
+	case "name":
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Name < files[j].Name
+		})
+	case "blank":
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].Blanks == files[j].Blanks {
+				return files[i].Name < files[j].Name
+			}
+			return files[i].Blanks > files[j].Blanks
+		})
+	case "comment":
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].Comments == files[j].Comments {
+				return files[i].Name < files[j].Name
+			}
+			return files[i].Comments > files[j].Comments
+		})
+	default:
+		sort.Sort(files)
+	}
+}
+
+// validateOptions reports invalid --output-type and --sort values.
+func validateOptions(opts *CmdOptions) error {
+	switch opts.OutputType {
+	case OutputTypeDefault, OutputTypeClocXML, OutputTypeSloccount, OutputTypeJSON:
+	default:
+		return fmt.Errorf("invalid --output-type: %q", opts.OutputType)
+	}
+	switch opts.SortTag {
+	case "name", "files", "blank", "comment", "code":
+	default:
+		return fmt.Errorf("invalid --sort: %q", opts.SortTag)
+	}
+	if opts.ReportDups && opts.OutputType != OutputTypeDefault && opts.OutputType != OutputTypeJSON {
+		return fmt.Errorf("--report-duplicates does not support --output-type=%s", opts.OutputType)
+	}
+	if opts.Diff && opts.OutputType == OutputTypeSloccount {
+		return fmt.Errorf("--diff does not support --output-type=%s", OutputTypeSloccount)
+	}
+	if strings.Contains(opts.GitRev, "..") && !opts.Diff {
+		return fmt.Errorf("--git-rev=%s needs --diff", opts.GitRev)
+	}
+	if opts.CPD && opts.Diff {
+		return fmt.Errorf("--cpd and --diff cannot be used together")
+	}
+	if opts.CPD && opts.OutputType != OutputTypeDefault && opts.OutputType != OutputTypeJSON {
+		return fmt.Errorf("--cpd does not support --output-type=%s", opts.OutputType)
+	}
+	return nil
+}
+
+// diffStatuses are the rows of each language or file of --diff.
+var diffStatuses = []string{gocloc.DiffSame, gocloc.DiffModified, gocloc.DiffAdded, gocloc.DiffRemoved}
+
+func diffCountOf(c gocloc.DiffCount, status string) int32 {
+	switch status {
+	case gocloc.DiffSame:
+		return c.Same
+	case gocloc.DiffModified:
+		return c.Modified
+	case gocloc.DiffAdded:
+		return c.Added
+	}
+	return c.Removed
+}
+
+// runDiff compares the two paths, or paths at the two revisions of --git-rev=OLD..NEW.
+func runDiff(processor *gocloc.Processor, paths []string, opts *CmdOptions) (*gocloc.DiffResult, error) {
+	if oldRev, newRev, ok := strings.Cut(opts.GitRev, ".."); ok {
+		return processor.DiffRevisions(oldRev, newRev, paths)
+	}
+	if len(paths) != 2 {
+		return nil, fmt.Errorf("--diff needs two paths, the old and the new one")
+	}
+	return processor.Diff(paths[:1], paths[1:])
+}
+
+// writeDiffResult writes the result of --diff.
+func writeDiffResult(result *gocloc.DiffResult, opts *CmdOptions) {
+	switch opts.OutputType {
+	case OutputTypeClocXML:
+		option := gocloc.XMLResultWithLangs
+		if opts.Byfile {
+			option = gocloc.XMLResultWithFiles
+		}
+		gocloc.NewXMLDiffResult(result, option).Encode()
+	case OutputTypeJSON:
+		var jsonResult interface{} = gocloc.NewJSONDiffLanguagesResult(result)
+		if opts.Byfile {
+			jsonResult = gocloc.NewJSONDiffFilesResult(result)
+		}
+		buf, err := json.Marshal(jsonResult)
+		if err != nil {
+			fmt.Println(err)
+			panic("json marshal error")
+		}
+		os.Stdout.Write(buf)
+	default:
+		writeDiffText(result, opts)
+	}
+}
+
+// writeDiffText writes the rows of each status under each language, or each file with --by-file.
+func writeDiffText(result *gocloc.DiffResult, opts *CmdOptions) {
+	headerLen := 28
+	header := languageHeader
+	if opts.Byfile {
+		headerLen = result.MaxPathLength + 1
+		if headerLen < len("TOTAL")+1 {
+			headerLen = len("TOTAL") + 1
+		}
+		rowLen = headerLen + len(commonHeader) + 1
+		header = fileHeader
+	}
+	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	fmt.Printf("%-[2]*[1]s %[3]s\n", header, headerLen, commonHeader)
+	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+
+	writeRows := func(name string, files *gocloc.DiffCount, blanks, comments, code gocloc.DiffCount) {
+		fmt.Println(name)
+		for _, status := range diffStatuses {
+			if opts.Byfile {
+				fmt.Printf("%-[1]*[2]s %21[3]v %14[4]v %14[5]v\n", headerLen-1, " "+status,
+					diffCountOf(blanks, status), diffCountOf(comments, status), diffCountOf(code, status))
+			} else {
+				fmt.Printf("%-27v %6v %14v %14v %14v\n", " "+status, diffCountOf(*files, status),
+					diffCountOf(blanks, status), diffCountOf(comments, status), diffCountOf(code, status))
+			}
+		}
+	}
+	if opts.Byfile {
+		for _, file := range result.SortedDiffFiles() {
+			writeRows(file.Name, nil, file.Blanks, file.Comments, file.Code)
+		}
+	} else {
+		for _, language := range result.SortedDiffLanguages() {
+			writeRows(language.Name, &language.Files, language.Blanks, language.Comments, language.Code)
+		}
+	}
+
+	total := result.Total
+	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+	writeRows("TOTAL", &total.Files, total.Blanks, total.Comments, total.Code)
+	fmt.Printf("%.[2]*[1]s\n", defaultOutputSeparator, rowLen)
+}
+
+// compileRegexp compiles the value of a regex option, an empty value means no filter.
+func compileRegexp(name, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression for --%s: %w", name, err)
+	}
+	return re, nil
+}
+
+// setupClocOptions applies the command line options to clocOpts.
+func setupClocOptions(opts *CmdOptions, languages *gocloc.DefinedLanguages, clocOpts *gocloc.ClocOptions) (err error) {
+	// setup option for exclude extensions
+	for _, ext := range strings.Split(opts.ExcludeExt, ",") {
+		if ext == "" {
+			continue
+		}
+		e, ok := languages.ExtLanguage(ext)
+		if ok {
+			clocOpts.ExcludeExts[e] = struct{}{}
+		} else {
+			clocOpts.ExcludeExts[ext] = struct{}{}
+		}
+	}
+
+	// directory and file matching options
+	if clocOpts.ReMatch, err = compileRegexp("match", opts.Match); err != nil {
+		return err
+	}
+	if clocOpts.ReNotMatch, err = compileRegexp("not-match", opts.NotMatch); err != nil {
+		return err
+	}
+	if clocOpts.ReMatchDir, err = compileRegexp("match-d", opts.MatchDir); err != nil {
+		return err
+	}
+	if clocOpts.ReNotMatchDir, err = compileRegexp("not-match-d", opts.NotMatchDir); err != nil {
+		return err
+	}
+
+	if opts.VCSDirs != "" {
+		clocOpts.VCSDirs = []string{}
+		for _, dir := range strings.Split(opts.VCSDirs, ",") {
+			if dir != "" {
+				clocOpts.VCSDirs = append(clocOpts.VCSDirs, dir)
+			}
+		}
+	}
+
+	// setup option for exclusion presets
+	for _, name := range strings.Split(opts.ExcludePreset, ",") {
+		if name == "" {
+			continue
+		}
+		if err := clocOpts.AddExcludePreset(name); err != nil {
+			return err
+		}
+	}
+
+	// setup option for include languages
+	for _, lang := range strings.Split(opts.IncludeLang, ",") {
+		if lang == "" {
+			continue
+		}
+		if _, ok := languages.Langs[lang]; !ok {
+			return fmt.Errorf("unknown language for --include-lang: %q", lang)
+		}
+		clocOpts.IncludeLangs[lang] = struct{}{}
+	}
+
+	clocOpts.Debug = opts.Debug
+	clocOpts.SkipDuplicated = opts.SkipDuplicated
+	clocOpts.NoIgnore = opts.NoIgnore
+
+	clocOpts.UseEnry = opts.Enry
+	clocOpts.Embedded = opts.Embedded
+
+	clocOpts.SkipBinary = opts.SkipBinary
+	clocOpts.SkipGenerated = opts.SkipGenerated
+	clocOpts.SkipMinified = opts.SkipMinified
+	clocOpts.SkipVendored = opts.SkipVendored
+	clocOpts.CacheDir = opts.Cache
+	clocOpts.ResetCache = opts.ResetCache
+	clocOpts.OnCacheError = func(err error) {
+		fmt.Fprintf(os.Stderr, "fail to save the cache: %s\n", err)
+	}
+	if !opts.Diff {
+		// --diff reads the revisions of --git-rev itself
+		clocOpts.GitRev = opts.GitRev
+	}
+
+	if opts.Jobs < 0 {
+		return fmt.Errorf("invalid --jobs: %d", opts.Jobs)
+	}
+	clocOpts.Jobs = opts.Jobs
+	if clocOpts.Jobs == 0 {
+		clocOpts.Jobs = runtime.NumCPU()
+	}
+	return nil
+}
+
+func mergeLanguageDefinitions(languages *gocloc.DefinedLanguages, path string, force bool) error {
+	defs, err := gocloc.ReadLanguageDefinitionFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, conflict := range languages.Merge(defs, force) {
+		if force {
+			fmt.Fprintf(os.Stderr, "%s: override: %s\n", path, conflict)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: skip: %s\n", path, conflict)
+		}
+	}
+	return nil
 }
 
 func main() {
@@ -80,19 +622,91 @@ func main() {
 	parser.Name = "gocloc"
 	parser.Usage = "[OPTIONS] PATH[...]"
 
-	paths, err := flags.Parse(&opts)
+	paths, err := parser.Parse()
 	if err != nil {
+		// the parser printed the error or the help
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+
+	if opts.ShowVersion {
+		fmt.Printf("%s (%s)\n", Version, GitCommit)
 		return
 	}
 
 	// value for language result
 	languages := gocloc.NewDefinedLanguages()
 
+	// user-defined languages
+	if opts.ReadLangDef != "" {
+		if err := mergeLanguageDefinitions(languages, opts.ReadLangDef, false); err != nil {
+			fmt.Fprintf(os.Stderr, "fail read language definitions. error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.ForceLangDef != "" {
+		if err := mergeLanguageDefinitions(languages, opts.ForceLangDef, true); err != nil {
+			fmt.Fprintf(os.Stderr, "fail read language definitions. error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.ShowLang {
+		fmt.Println(languages.GetFormattedString())
+		return
+	}
+
+	if opts.ListPresets {
+		fmt.Print(gocloc.GetFormattedPresets())
+		return
+	}
+
+	if len(paths) <= 0 {
+		parser.WriteHelp(os.Stdout)
+		return
+	}
+
+	if err := validateOptions(&opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := setupClocOptions(&opts, languages, clocOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	processor := gocloc.NewProcessor(languages, clocOpts)
+	if opts.Diff {
+		result, err := runDiff(processor, paths, &opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fail gocloc diff. error: %v\n", err)
+			os.Exit(1)
+		}
+		writeDiffResult(result, &opts)
+		return
+	}
+
+	if opts.CPD {
+		result, err := processor.DetectClones(paths, opts.CPDMinLines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fail gocloc cpd. error: %v\n", err)
+			os.Exit(1)
+		}
+		buf, err := json.Marshal(gocloc.NewJSONCPDResult(result))
+		if err != nil {
+			fmt.Println(err)
+			panic("json marshal error")
+		}
+		os.Stdout.Write(buf)
+		return
+	}
+
 	result, err := processor.Analyze(paths)
 	if err != nil {
-		fmt.Printf("fail gocloc analyze. error: %v\n", err)
-		return
+		fmt.Fprintf(os.Stderr, "fail gocloc analyze. error: %v\n", err)
+		os.Exit(1)
 	}
 
 	builder := newOutputBuilder(result, &opts)
@@ -0,0 +1,339 @@
+package gocloc
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultCPDMinLines is the default length of the duplicated blocks, in code lines.
+const DefaultCPDMinLines = 6
+
+// CloneLocation is a copy of a duplicated block, StartLine and EndLine are the lines in the file.
+type CloneLocation struct {
+	Name      string `json:"name"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// CloneGroup is a block of code lines found in several places.
+type CloneGroup struct {
+	Lang      string          `json:"language"`
+	Lines     int             `json:"lines"`
+	Locations []CloneLocation `json:"locations"`
+}
+
+// CPDLanguage is the number of code lines of a language inside duplicated blocks.
+type CPDLanguage struct {
+	Name       string  `json:"name,omitempty"`
+	Code       int32   `json:"code"`
+	Duplicated int32   `json:"duplicated"`
+	Percentage float64 `json:"percentage"`
+}
+
+func (l *CPDLanguage) add(code, duplicated int32) {
+	l.Code += code
+	l.Duplicated += duplicated
+	if l.Code > 0 {
+		l.Percentage = math.Round(float64(l.Duplicated)*10000/float64(l.Code)) / 100
+	}
+}
+
+// CPDResult defined the result of Processor.DetectClones.
+type CPDResult struct {
+	// Clones are sorted by the number of lines, the longest first.
+	Clones    []CloneGroup
+	Languages map[string]*CPDLanguage
+	Total     *CPDLanguage
+}
+
+// cpdFile is the code of a file for the detection of the duplicated blocks.
+type cpdFile struct {
+	name string
+	lang string
+	// lineNumbers are the numbers of the code lines, code are the code lines without
+	// their comments and with their spaces collapsed.
+	lineNumbers []int
+	code        []string
+	// keys are the hashes of the windows of code lines starting at each code line.
+	keys    []uint64
+	covered []bool
+}
+
+type cpdOccurrence struct {
+	file  int
+	start int
+}
+
+// DetectClones finds the blocks of at least minLines code lines that are repeated in the files
+// of paths, like a copy/paste detector. The code lines are compared without comments, and
+// with their spaces collapsed. minLines less than 2 means DefaultCPDMinLines.
+// The copies of a whole file are clones of the length of the file.
+func (p *Processor) DetectClones(paths []string, minLines int) (*CPDResult, error) {
+	return p.DetectClonesContext(context.Background(), paths, minLines)
+}
+
+// DetectClonesContext is like DetectClones, but stops as soon as ctx is done, and returns ctx.Err().
+func (p *Processor) DetectClonesContext(ctx context.Context, paths []string, minLines int) (*CPDResult, error) {
+	if minLines < 2 {
+		minLines = DefaultCPDMinLines
+	}
+	opts := *p.opts
+	// the clones are reported in the language of their file
+	opts.Embedded = false
+	// the duplicated files are copies of code too
+	opts.SkipDuplicated = true
+	if opts.GitRev != "" {
+		gitOpts, err := gitRevisionOptions(&opts, paths)
+		if err != nil {
+			return nil, err
+		}
+		opts = *gitOpts
+	}
+
+	files, err := p.readCPDFiles(ctx, paths, &opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		f.keys = windowKeys(f.code, minLines)
+		f.covered = make([]bool, len(f.code))
+	}
+
+	result := &CPDResult{
+		Clones:    findClones(files, minLines),
+		Languages: make(map[string]*CPDLanguage),
+		Total:     &CPDLanguage{},
+	}
+	for _, f := range files {
+		var duplicated int32
+		for _, covered := range f.covered {
+			if covered {
+				duplicated++
+			}
+		}
+		language, ok := result.Languages[f.lang]
+		if !ok {
+			language = &CPDLanguage{Name: f.lang}
+			result.Languages[f.lang] = language
+		}
+		language.add(int32(len(f.code)), duplicated)
+		result.Total.add(int32(len(f.code)), duplicated)
+	}
+	return result, nil
+}
+
+// readCPDFiles reads the code lines of the files of paths, sorted by name.
+func (p *Processor) readCPDFiles(ctx context.Context, paths []string, opts *ClocOptions) ([]*cpdFile, error) {
+	var tasks []analyzeTask
+	_, err := getAllFiles(ctx, paths, p.langs, opts, func(path string, language *Language) {
+		tasks = append(tasks, analyzeTask{file: path, language: language})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].file < tasks[j].file
+	})
+
+	jobs := opts.Jobs
+	if opts.Debug || jobs < 1 {
+		jobs = 1
+	}
+	files := make([]*cpdFile, len(tasks))
+	errs := make([]error, len(tasks))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				files[i], errs[i] = readCPDFile(ctx, tasks[i], opts)
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readCPDFile classifies the lines of a file, keeping the code lines with their line numbers.
+func readCPDFile(ctx context.Context, task analyzeTask, opts *ClocOptions) (*cpdFile, error) {
+	f := &cpdFile{name: task.file, lang: task.language.Name}
+	lineNumber := 0
+	fileOpts := *opts
+	fileOpts.onCodeText = func(code string) {
+		lineNumber++
+		f.lineNumbers = append(f.lineNumbers, lineNumber)
+		f.code = append(f.code, strings.Join(strings.Fields(code), " "))
+	}
+	fileOpts.OnComment = func(line string) {
+		lineNumber++
+	}
+	fileOpts.OnBlank = func(line string) {
+		lineNumber++
+	}
+	_, err := analyzeFile(ctx, task.file, task.language, &fileOpts)
+	return f, err
+}
+
+// windowKeys returns the hashes of the windows of n code lines.
+func windowKeys(code []string, n int) []uint64 {
+	if len(code) < n {
+		return nil
+	}
+	keys := make([]uint64, len(code)-n+1)
+	for i := range keys {
+		h := fnv.New64a()
+		for _, line := range code[i : i+n] {
+			h.Write([]byte(line))
+			h.Write([]byte{'\n'})
+		}
+		keys[i] = h.Sum64()
+	}
+	return keys
+}
+
+// findClones groups the windows with the same code, and extends each group as long as all of
+// its copies go on alike, before and after.
+func findClones(files []*cpdFile, minLines int) []CloneGroup {
+	// index lists the windows by hash, without the windows overlapping an earlier one of the same file
+	index := make(map[uint64][]cpdOccurrence)
+	for fi, f := range files {
+		for i, key := range f.keys {
+			occs := index[key]
+			if n := len(occs); n > 0 && occs[n-1].file == fi && i < occs[n-1].start+minLines {
+				continue
+			}
+			index[key] = append(occs, cpdOccurrence{file: fi, start: i})
+		}
+	}
+
+	var clones []CloneGroup
+	for fi, f := range files {
+		for i, key := range f.keys {
+			occs := index[key]
+			if len(occs) < 2 || occs[0] != (cpdOccurrence{file: fi, start: i}) {
+				// each group is found at its first window
+				continue
+			}
+			group := sameCode(files, occs, minLines)
+			if len(group) < 2 || extendsEarlierGroup(files, index, group) {
+				continue
+			}
+
+			// a group with fewer copies than the windows before it is a longer clone of its own
+			before := extendGroup(files, group, minLines, -1)
+			lines := before + minLines + extendGroup(files, group, minLines, 1)
+			clone := CloneGroup{Lang: f.lang, Lines: lines}
+			for _, occ := range group {
+				cf := files[occ.file]
+				start := occ.start - before
+				clone.Locations = append(clone.Locations, CloneLocation{
+					Name:      cf.name,
+					StartLine: cf.lineNumbers[start],
+					EndLine:   cf.lineNumbers[start+lines-1],
+				})
+				for j := start; j < start+lines; j++ {
+					cf.covered[j] = true
+				}
+			}
+			clones = append(clones, clone)
+		}
+	}
+
+	sort.SliceStable(clones, func(i, j int) bool {
+		return clones[i].Lines > clones[j].Lines
+	})
+	return clones
+}
+
+// sameCode returns the windows of occs with the code of the first one, leaving out hash collisions.
+func sameCode(files []*cpdFile, occs []cpdOccurrence, n int) []cpdOccurrence {
+	first := occs[0]
+	code := files[first.file].code[first.start : first.start+n]
+	group := []cpdOccurrence{first}
+	for _, occ := range occs[1:] {
+		other := files[occ.file].code[occ.start : occ.start+n]
+		same := true
+		for i := range code {
+			if code[i] != other[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			group = append(group, occ)
+		}
+	}
+	return group
+}
+
+// shiftedKey returns the hash of the windows of group shifted by delta, when they all exist
+// and have the same hash.
+func shiftedKey(files []*cpdFile, group []cpdOccurrence, delta int) (uint64, bool) {
+	var key uint64
+	for i, occ := range group {
+		j := occ.start + delta
+		keys := files[occ.file].keys
+		if j < 0 || j >= len(keys) {
+			return 0, false
+		}
+		if i == 0 {
+			key = keys[j]
+		} else if keys[j] != key {
+			return 0, false
+		}
+	}
+	return key, true
+}
+
+// extendsEarlierGroup reports whether the windows before group make the same group, so that
+// group is a part of a clone found before.
+func extendsEarlierGroup(files []*cpdFile, index map[uint64][]cpdOccurrence, group []cpdOccurrence) bool {
+	key, ok := shiftedKey(files, group, -1)
+	return ok && len(index[key]) == len(group)
+}
+
+// extendGroup returns the number of code lines the copies of group go on alike, after the
+// first window with step 1, or before it with step -1.
+func extendGroup(files []*cpdFile, group []cpdOccurrence, minLines, step int) int {
+	extra := 0
+	for {
+		if _, ok := shiftedKey(files, group, step*(extra+1)); !ok {
+			return extra
+		}
+		// the copies in a file must not overlap
+		for i := 1; i < len(group); i++ {
+			if group[i].file == group[i-1].file && group[i-1].start+minLines+extra+1 > group[i].start {
+				return extra
+			}
+		}
+		extra++
+	}
+}
+
+// SortedLanguages returns the languages of r sorted by name.
+func (r *CPDResult) SortedLanguages() []CPDLanguage {
+	langs := make([]CPDLanguage, 0, len(r.Languages))
+	for _, language := range r.Languages {
+		langs = append(langs, *language)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		return langs[i].Name < langs[j].Name
+	})
+	return langs
+}
@@ -0,0 +1,174 @@
+package gocloc
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeReaderCodeText(t *testing.T) {
+	content := "x  :=\t1\n" +
+		"x := 1 // one\n" +
+		"x := /* one */ 1\n" +
+		"x := 1 /* one\n" +
+		"two */ y := 2\n" +
+		"s := \"// not a comment\"\n" +
+		"s := \"a\\\" /* b */\"  // c\n" +
+		"r := `a\n" +
+		"// not a comment /*\n" +
+		"`  // c\n"
+	want := []string{
+		"x := 1",
+		"x := 1",
+		"x := 1",
+		"x := 1",
+		"y := 2",
+		`s := "// not a comment"`,
+		`s := "a\" /* b */"`,
+		"r := `a",
+		"// not a comment /*",
+		"`",
+	}
+
+	var got []string
+	opts := NewClocOptions()
+	opts.onCodeText = func(code string) {
+		got = append(got, strings.Join(strings.Fields(code), " "))
+	}
+	AnalyzeReader("test.go", NewDefinedLanguages().Langs["Go"], strings.NewReader(content), opts)
+	if len(got) != len(want) {
+		t.Fatalf("invalid logic. code=%q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("invalid logic. line=%d got=%q want=%q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectClones(t *testing.T) {
+	dir := t.TempDir()
+	block := "\tfor _, x := range xs {\n\t\tif x > 0 {\n\t\t\ttotal += x\n\t\t}\n\t}\n\treturn total\n}\n"
+	writeTestFiles(t, dir, map[string]string{
+		"a.go": "package a\n\nfunc sum(xs []int) int {\n\ttotal := 0\n" + block,
+		// the copy differs in spaces, comments and blank lines only
+		"b.go": "package b\n\n// add adds\nfunc add(xs []int) int {\n    total := 0 // start\n" +
+			strings.Replace(block, "{\n", "{ /* loop */\n\n", 1),
+		// overlapping repeated lines do not make a clone of themselves
+		"c.go": "package c\n\nfunc c() {\n" + strings.Repeat("\tprintln()\n", 8) + "}\n",
+		"d.py": "x = 1\ny = 2\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DetectClones([]string{dir}, 5)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.Clones) != 1 {
+		t.Fatalf("invalid logic. clones=%+v", result.Clones)
+	}
+	clone := result.Clones[0]
+	want := []CloneLocation{
+		{Name: filepath.Join(dir, "a.go"), StartLine: 4, EndLine: 11},
+		{Name: filepath.Join(dir, "b.go"), StartLine: 5, EndLine: 13},
+	}
+	if clone.Lang != "Go" || clone.Lines != 8 || len(clone.Locations) != 2 || clone.Locations[0] != want[0] || clone.Locations[1] != want[1] {
+		t.Errorf("invalid logic. clone=%+v", clone)
+	}
+
+	golang := result.Languages["Go"]
+	if golang.Code != 31 || golang.Duplicated != 16 || golang.Percentage != 51.61 {
+		t.Errorf("invalid logic. Go=%+v", golang)
+	}
+	if python := result.Languages["Python"]; python.Code != 2 || python.Duplicated != 0 || python.Percentage != 0 {
+		t.Errorf("invalid logic. Python=%+v", python)
+	}
+	if result.Total.Code != 33 || result.Total.Duplicated != 16 {
+		t.Errorf("invalid logic. total=%+v", result.Total)
+	}
+
+	// a block longer than the clone finds nothing
+	result, err = NewProcessor(NewDefinedLanguages(), NewClocOptions()).DetectClones([]string{dir}, 9)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.Clones) != 0 || result.Total.Duplicated != 0 {
+		t.Errorf("invalid logic. clones=%+v", result.Clones)
+	}
+
+	buf, err := json.Marshal(NewJSONCPDResult(result))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if !strings.HasPrefix(string(buf), `{"clones":[],"languages":[{"name":"Go"`) {
+		t.Errorf("invalid logic. json=%s", buf)
+	}
+}
+
+func TestDetectClonesGroups(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 0; i < 8; i++ {
+		lines = append(lines, "f"+strings.Repeat("x", i)+"()")
+	}
+	block := strings.Join(lines, "\n") + "\n"
+	writeTestFiles(t, dir, map[string]string{
+		"a.py": block,
+		"b.py": "a = 1\n" + block + "b = 2\n",
+		// twice in the same file, with only the first lines in the third file
+		"c.py": block + "c = 3\n" + block,
+		"d.py": strings.Join(lines[:6], "\n") + "\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DetectClones([]string{dir}, 0)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.Clones) != 2 {
+		t.Fatalf("invalid logic. clones=%+v", result.Clones)
+	}
+	if c := result.Clones[0]; c.Lines != 8 || len(c.Locations) != 4 || c.Locations[3] != (CloneLocation{Name: filepath.Join(dir, "c.py"), StartLine: 10, EndLine: 17}) {
+		t.Errorf("invalid logic. clone=%+v", c)
+	}
+	if c := result.Clones[1]; c.Lines != 6 || len(c.Locations) != 5 {
+		t.Errorf("invalid logic. clone=%+v", c)
+	}
+	if python := result.Languages["Python"]; python.Duplicated != 38 || python.Code != 41 {
+		t.Errorf("invalid logic. Python=%+v", python)
+	}
+}
+
+func TestDetectClonesDuplicatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	src := "package a\n\nfunc f(xs []int) int {\n\ttotal := 0\n\tfor _, x := range xs {\n\t\ttotal += x\n\t}\n\treturn total\n}\n"
+	writeTestFiles(t, dir, map[string]string{
+		"a.go":     src,
+		"copy.go":  src,
+		"other.go": "package b\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DetectClones([]string{dir}, 5)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.Clones) != 1 {
+		t.Fatalf("invalid logic. clones=%+v", result.Clones)
+	}
+	want := []CloneLocation{
+		{Name: filepath.Join(dir, "a.go"), StartLine: 1, EndLine: 9},
+		{Name: filepath.Join(dir, "copy.go"), StartLine: 1, EndLine: 9},
+	}
+	if c := result.Clones[0]; c.Lines != 8 || len(c.Locations) != 2 || c.Locations[0] != want[0] || c.Locations[1] != want[1] {
+		t.Errorf("invalid logic. clone=%+v", c)
+	}
+	if result.Total.Code != 17 || result.Total.Duplicated != 16 {
+		t.Errorf("invalid logic. total=%+v", result.Total)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DetectClonesContext(ctx, []string{dir}, 5); err != context.Canceled {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+}
@@ -0,0 +1,200 @@
+package gocloc
+
+import (
+	"crypto/sha256"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// dedupQueueSize bounds the files of the walk waiting for the decision of the duplicate detector.
+const dedupQueueSize = 256
+
+// duplicateDetector skips the files with the content of a file found earlier by the walk.
+// Only the files of the same size are hashed, concurrently while the walk goes on, and
+// the files are accepted in the order of the walk.
+type duplicateDetector struct {
+	fs afero.Fs
+	// bySize are the files added so far by size, used by the walk goroutine only.
+	bySize map[int64][]*dedupEntry
+	// hashing bounds the files hashed at the same time.
+	hashing chan struct{}
+	queue   chan *dedupEntry
+	done    chan struct{}
+}
+
+type dedupEntry struct {
+	path   string
+	accept func()
+	// peers are the files of the same size added before.
+	peers []*dedupEntry
+
+	hashOnce sync.Once
+	hashed   chan struct{}
+	hash     []byte
+
+	// original is the file with the same content when the entry is a duplicate, set in queue order.
+	original string
+}
+
+// newDuplicateDetector starts the detector, onDuplicate is called for each skipped file.
+func newDuplicateDetector(fs afero.Fs, onDuplicate func(path, original string)) *duplicateDetector {
+	d := &duplicateDetector{
+		fs:      fs,
+		bySize:  make(map[int64][]*dedupEntry),
+		hashing: make(chan struct{}, runtime.NumCPU()),
+		queue:   make(chan *dedupEntry, dedupQueueSize),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(d.done)
+		for e := range d.queue {
+			if e.original = d.originalOf(e); e.original == "" {
+				e.accept()
+			} else if onDuplicate != nil {
+				onDuplicate(e.path, e.original)
+			}
+		}
+	}()
+	return d
+}
+
+// add queues the file path, accept is called unless it is a duplicate of an earlier file.
+func (d *duplicateDetector) add(path string, accept func()) {
+	e := &dedupEntry{path: path, accept: accept, hashed: make(chan struct{})}
+	if info, err := d.fs.Stat(path); err == nil {
+		size := info.Size()
+		e.peers = d.bySize[size]
+		d.bySize[size] = append(d.bySize[size], e)
+		if len(e.peers) > 0 {
+			// only the files sharing their size with another file are hashed
+			d.startHash(e.peers[0])
+			d.startHash(e)
+		}
+	}
+	d.queue <- e
+}
+
+// wait returns after the decision of all the files added.
+func (d *duplicateDetector) wait() {
+	close(d.queue)
+	<-d.done
+}
+
+func (d *duplicateDetector) startHash(e *dedupEntry) {
+	e.hashOnce.Do(func() {
+		go func() {
+			d.hashing <- struct{}{}
+			e.hash = hashFile(d.fs, e.path)
+			<-d.hashing
+			close(e.hashed)
+		}()
+	})
+}
+
+// originalOf returns the earlier file with the content of e, or "".
+func (d *duplicateDetector) originalOf(e *dedupEntry) string {
+	if len(e.peers) == 0 {
+		return ""
+	}
+	<-e.hashed
+	if e.hash == nil {
+		return ""
+	}
+	for _, peer := range e.peers {
+		if peer.original != "" {
+			// a duplicate is compared through its original
+			continue
+		}
+		d.startHash(peer)
+		<-peer.hashed
+		if peer.hash != nil && string(peer.hash) == string(e.hash) {
+			return peer.path
+		}
+	}
+	return ""
+}
+
+// hashFile returns the SHA-256 of the content of path read as a stream, or nil when it cannot be read.
+func hashFile(fs afero.Fs, path string) []byte {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil
+	}
+	return h.Sum(nil)
+}
+
+// Duplicates are the files skipped as duplicates, grouped by the counted file with their content.
+type Duplicates struct {
+	// Files maps each counted file to its copies, sorted by name.
+	Files map[string][]string
+	// Languages are the lines of the copies, by the language of the counted file.
+	Languages map[string]*ClocLanguage
+	Total     *ClocLanguage
+}
+
+// newDuplicates groups the copies of duplicateOf, counting their lines as the lines of their original.
+func newDuplicates(duplicateOf map[string]string, clocFiles map[string]*ClocFile) *Duplicates {
+	d := &Duplicates{
+		Files:     make(map[string][]string),
+		Languages: make(map[string]*ClocLanguage),
+		Total:     &ClocLanguage{},
+	}
+	for file, original := range duplicateOf {
+		cf, ok := clocFiles[original]
+		if !ok {
+			// the original was not counted before the cancellation
+			continue
+		}
+		d.Files[original] = append(d.Files[original], file)
+
+		language, ok := d.Languages[cf.Lang]
+		if !ok {
+			language = &ClocLanguage{Name: cf.Lang}
+			d.Languages[cf.Lang] = language
+		}
+		for _, l := range []*ClocLanguage{language, d.Total} {
+			l.FilesCount++
+			l.Code += cf.Code
+			l.Comments += cf.Comments
+			l.DocComments += cf.DocComments
+			l.Blanks += cf.Blanks
+			l.Mixed += cf.Mixed
+		}
+	}
+	for _, copies := range d.Files {
+		sort.Strings(copies)
+	}
+	return d
+}
+
+// SortedLanguages returns the languages of the copies sorted by name.
+func (d *Duplicates) SortedLanguages() []ClocLanguage {
+	langs := make([]ClocLanguage, 0, len(d.Languages))
+	for _, language := range d.Languages {
+		langs = append(langs, *language)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		return langs[i].Name < langs[j].Name
+	})
+	return langs
+}
+
+// SortedOriginals returns the counted files with copies sorted by name.
+func (d *Duplicates) SortedOriginals() []string {
+	originals := make([]string, 0, len(d.Files))
+	for original := range d.Files {
+		originals = append(originals, original)
+	}
+	sort.Strings(originals)
+	return originals
+}
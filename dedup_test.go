@@ -0,0 +1,128 @@
+package gocloc
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestDuplicateDetector(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := []struct{ name, content string }{
+		{"a.go", "package a\n"},
+		{"b.go", "package a\n"},
+		{"c.go", "package c\n"},
+		{"d.go", "package d // unique size\n"},
+		{"e.go", "package a\n"},
+		{"f.go", "package c\n"},
+	}
+	for _, f := range files {
+		if err := afero.WriteFile(fs, f.name, []byte(f.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var accepted []string
+	duplicates := make(map[string]string)
+	d := newDuplicateDetector(fs, func(path, original string) {
+		duplicates[path] = original
+	})
+	for _, f := range files {
+		name := f.name
+		d.add(name, func() {
+			accepted = append(accepted, name)
+		})
+	}
+	d.wait()
+
+	if want := []string{"a.go", "c.go", "d.go"}; !reflect.DeepEqual(accepted, want) {
+		t.Errorf("invalid logic. accepted=%v", accepted)
+	}
+	if want := map[string]string{"b.go": "a.go", "e.go": "a.go", "f.go": "c.go"}; !reflect.DeepEqual(duplicates, want) {
+		t.Errorf("invalid logic. duplicates=%v", duplicates)
+	}
+	for _, e := range d.bySize[int64(len("package d // unique size\n"))] {
+		if e.hash != nil {
+			t.Errorf("invalid logic. the file of a unique size is hashed: %v", e.path)
+		}
+	}
+}
+
+func TestAnalyzeReportsDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a/main.go":   "package main\n\nfunc main() {}\n",
+		"b/main.go":   "package main\n\nfunc main() {}\n",
+		"b/other.go":  "package main\n\nfunc other() {}\n",
+		"c/script.py": "x = 1\n",
+	})
+
+	for _, jobs := range []int{1, 4} {
+		opts := NewClocOptions()
+		opts.Jobs = jobs
+		result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+		if err != nil {
+			t.Fatalf("invalid logic. err=[%v]", err)
+		}
+		want := map[string]string{filepath.Join(dir, "b", "main.go"): filepath.Join(dir, "a", "main.go")}
+		if !reflect.DeepEqual(result.DuplicateOf, want) {
+			t.Errorf("invalid logic. jobs=%v duplicateOf=%v", jobs, result.DuplicateOf)
+		}
+		if result.Total.Total != 3 || result.Total.Code != 5 {
+			t.Errorf("invalid logic. jobs=%v total=%v", jobs, result.Total)
+		}
+	}
+
+	opts := NewClocOptions()
+	opts.SkipDuplicated = true
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.DuplicateOf) != 0 || result.Total.Total != 4 {
+		t.Errorf("invalid logic. duplicateOf=%v total=%v", result.DuplicateOf, result.Total)
+	}
+}
+
+func TestAnalyzeDuplicatesByLanguage(t *testing.T) {
+	dir := t.TempDir()
+	goSrc := "package main\n\n// main\nfunc main() {}\n"
+	writeTestFiles(t, dir, map[string]string{
+		"a/main.go":   goSrc,
+		"b/main.go":   goSrc,
+		"c/main.go":   goSrc,
+		"a/script.py": "x = 1\n",
+		"b/script.py": "x = 1\n",
+		"a/unique.py": "y = 2\n\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	duplicates := result.Duplicates
+	wantFiles := map[string][]string{
+		filepath.Join(dir, "a", "main.go"):   {filepath.Join(dir, "b", "main.go"), filepath.Join(dir, "c", "main.go")},
+		filepath.Join(dir, "a", "script.py"): {filepath.Join(dir, "b", "script.py")},
+	}
+	if !reflect.DeepEqual(duplicates.Files, wantFiles) {
+		t.Errorf("invalid logic. files=%v", duplicates.Files)
+	}
+	golang := duplicates.Languages["Go"]
+	if golang == nil || golang.FilesCount != 2 || golang.Code != 4 || golang.Comments != 2 || golang.Blanks != 2 {
+		t.Errorf("invalid logic. Go=%+v", golang)
+	}
+	if total := duplicates.Total; total.FilesCount != 3 || total.Code != 5 {
+		t.Errorf("invalid logic. total=%+v", total)
+	}
+
+	jsonDuplicates := NewJSONDuplicates(result)
+	if len(jsonDuplicates.Files) != 2 || jsonDuplicates.Files[0].Lang != "Go" || len(jsonDuplicates.Files[0].Copies) != 2 {
+		t.Errorf("invalid logic. json=%+v", jsonDuplicates)
+	}
+	if len(jsonDuplicates.Languages) != 2 || jsonDuplicates.Languages[1].Name != "Python" {
+		t.Errorf("invalid logic. json languages=%+v", jsonDuplicates.Languages)
+	}
+}
@@ -0,0 +1,500 @@
+package gocloc
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Status of a file in DiffResult.
+const (
+	DiffSame     = "same"
+	DiffModified = "modified"
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+)
+
+// maxDiffEdits bounds the edits searched between the lines of two files. The files differing
+// more are compared by their sets of lines.
+const maxDiffEdits = 1000
+
+// DiffCount is the number of lines or files that are the same, modified, added and removed.
+type DiffCount struct {
+	Same     int32 `xml:"same,attr" json:"same"`
+	Modified int32 `xml:"modified,attr" json:"modified"`
+	Added    int32 `xml:"added,attr" json:"added"`
+	Removed  int32 `xml:"removed,attr" json:"removed"`
+}
+
+func (c *DiffCount) add(o DiffCount) {
+	c.Same += o.Same
+	c.Modified += o.Modified
+	c.Added += o.Added
+	c.Removed += o.Removed
+}
+
+// ClocDiffFile is the difference of a file between the old and the new paths.
+type ClocDiffFile struct {
+	Name     string    `xml:"name,attr" json:"name"`
+	Lang     string    `xml:"language,attr" json:"language"`
+	Status   string    `xml:"status,attr" json:"status"`
+	Blanks   DiffCount `xml:"blank" json:"blank"`
+	Comments DiffCount `xml:"comment" json:"comment"`
+	Code     DiffCount `xml:"code" json:"code"`
+	// key is the path relative to the compared path, that pairs the old and the new file.
+	key string
+}
+
+// diffStatusOrder orders the files of the same path, the removed file of a changed language first.
+var diffStatusOrder = map[string]int{
+	DiffRemoved:  0,
+	DiffSame:     1,
+	DiffModified: 1,
+	DiffAdded:    2,
+}
+
+// lessDiffFile orders the files by their relative path, then by status.
+func lessDiffFile(a, b *ClocDiffFile) bool {
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return diffStatusOrder[a.Status] < diffStatusOrder[b.Status]
+}
+
+// ClocDiffLanguage is the difference of the files of a language, Files counts the files by status.
+type ClocDiffLanguage struct {
+	Name     string    `xml:"name,attr,omitempty" json:"name,omitempty"`
+	Files    DiffCount `xml:"files" json:"files"`
+	Blanks   DiffCount `xml:"blank" json:"blank"`
+	Comments DiffCount `xml:"comment" json:"comment"`
+	Code     DiffCount `xml:"code" json:"code"`
+}
+
+func (l *ClocDiffLanguage) add(file *ClocDiffFile) {
+	switch file.Status {
+	case DiffSame:
+		l.Files.Same++
+	case DiffModified:
+		l.Files.Modified++
+	case DiffAdded:
+		l.Files.Added++
+	case DiffRemoved:
+		l.Files.Removed++
+	}
+	l.Blanks.add(file.Blanks)
+	l.Comments.add(file.Comments)
+	l.Code.add(file.Code)
+}
+
+// DiffResult defined the result of Processor.Diff.
+type DiffResult struct {
+	Total *ClocDiffLanguage
+	// Files are sorted by their path relative to the compared paths, so that the old and the
+	// new version of a file are adjacent. A file whose language changed is both removed and
+	// added, with the removed one first.
+	Files         []*ClocDiffFile
+	Languages     map[string]*ClocDiffLanguage
+	MaxPathLength int
+}
+
+// Diff compares the files of oldPaths with the files of newPaths, paired by their path relative
+// to the given path, and counts the same, modified, added and removed lines of code, comments
+// and blanks of each file and language. Lines are compared without leading and trailing spaces,
+// a removed line followed by an added one is a modified line.
+func (p *Processor) Diff(oldPaths, newPaths []string) (*DiffResult, error) {
+	return p.diff(context.Background(), p.opts, p.opts, oldPaths, newPaths)
+}
+
+// DiffContext is like Diff, but stops walking and comparing as soon as ctx is done, and returns ctx.Err().
+func (p *Processor) DiffContext(ctx context.Context, oldPaths, newPaths []string) (*DiffResult, error) {
+	return p.diff(ctx, p.opts, p.opts, oldPaths, newPaths)
+}
+
+// DiffRevisions is like Diff, but compares the files of paths at the git revisions oldRev and newRev.
+func (p *Processor) DiffRevisions(oldRev, newRev string, paths []string) (*DiffResult, error) {
+	return p.DiffRevisionsContext(context.Background(), oldRev, newRev, paths)
+}
+
+// DiffRevisionsContext is like DiffRevisions, but stops as soon as ctx is done, and returns ctx.Err().
+func (p *Processor) DiffRevisionsContext(ctx context.Context, oldRev, newRev string, paths []string) (*DiffResult, error) {
+	oldOpts, newOpts := *p.opts, *p.opts
+	oldOpts.GitRev = oldRev
+	newOpts.GitRev = newRev
+	return p.diff(ctx, &oldOpts, &newOpts, paths, paths)
+}
+
+// diffSide is a file of one side of the diff.
+type diffSide struct {
+	file     string
+	language *Language
+	opts     *ClocOptions
+}
+
+// diffFiles returns the files of paths keyed by their path relative to paths.
+// Duplicated files are kept, a copy of a file is not an added file.
+func (p *Processor) diffFiles(ctx context.Context, opts *ClocOptions, paths []string) (map[string]*diffSide, error) {
+	if opts.GitRev != "" {
+		var err error
+		if opts, err = gitRevisionOptions(opts, paths); err != nil {
+			return nil, err
+		}
+	}
+	sideOpts := *opts
+	sideOpts.SkipDuplicated = true
+	opts = &sideOpts
+	languages, err := getAllFiles(ctx, paths, p.langs, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*diffSide)
+	for _, language := range languages {
+		for _, file := range language.Files {
+			files[diffKey(paths, file)] = &diffSide{file: file, language: language, opts: opts}
+		}
+	}
+	return files, nil
+}
+
+// diffKey returns the path of file relative to the path of paths containing it.
+func diffKey(paths []string, file string) string {
+	for _, root := range paths {
+		rel, err := filepath.Rel(root, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			// the path is the file itself
+			return filepath.Base(file)
+		}
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(file)
+}
+
+func (p *Processor) diff(ctx context.Context, oldOpts, newOpts *ClocOptions, oldPaths, newPaths []string) (*DiffResult, error) {
+	oldFiles, err := p.diffFiles(ctx, oldOpts, oldPaths)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := p.diffFiles(ctx, newOpts, newPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		seen := make(map[string]struct{})
+		for _, files := range []map[string]*diffSide{oldFiles, newFiles} {
+			for key := range files {
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					keys <- key
+				}
+			}
+		}
+	}()
+
+	jobs := p.opts.Jobs
+	if p.opts.Debug || jobs < 1 {
+		jobs = 1
+	}
+	var mu sync.Mutex
+	var firstErr error
+	var diffs []*ClocDiffFile
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				files, err := diffFile(ctx, oldFiles[key], newFiles[key])
+				for _, file := range files {
+					file.key = key
+				}
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				diffs = append(diffs, files...)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return lessDiffFile(diffs[i], diffs[j])
+	})
+	result := &DiffResult{
+		Total:     &ClocDiffLanguage{Name: "TOTAL"},
+		Files:     diffs,
+		Languages: make(map[string]*ClocDiffLanguage),
+	}
+	for _, file := range diffs {
+		if l := len(file.Name); result.MaxPathLength < l {
+			result.MaxPathLength = l
+		}
+		language, ok := result.Languages[file.Lang]
+		if !ok {
+			language = &ClocDiffLanguage{Name: file.Lang}
+			result.Languages[file.Lang] = language
+		}
+		language.add(file)
+		result.Total.add(file)
+	}
+	return result, nil
+}
+
+// classifiedLines are the lines of a file by kind, without leading and trailing spaces.
+type classifiedLines struct {
+	code     []string
+	comments []string
+	blanks   int32
+}
+
+func classifyFile(ctx context.Context, side *diffSide) (*classifiedLines, error) {
+	lines := &classifiedLines{}
+	opts := *side.opts
+	opts.OnCode = func(line string) {
+		lines.code = append(lines.code, line)
+	}
+	opts.OnComment = func(line string) {
+		lines.comments = append(lines.comments, line)
+	}
+	opts.OnBlank = func(line string) {
+		lines.blanks++
+	}
+	_, err := analyzeFile(ctx, side.file, side.language, &opts)
+	return lines, err
+}
+
+// diffFile compares the old and the new version of a file, either may be nil.
+// A file whose language changed is removed and added.
+func diffFile(ctx context.Context, oldSide, newSide *diffSide) ([]*ClocDiffFile, error) {
+	if oldSide != nil && newSide != nil && oldSide.language.Name != newSide.language.Name {
+		removed, err := diffFile(ctx, oldSide, nil)
+		if err != nil {
+			return nil, err
+		}
+		added, err := diffFile(ctx, nil, newSide)
+		return append(removed, added...), err
+	}
+
+	empty := &classifiedLines{}
+	oldLines, newLines := empty, empty
+	file := &ClocDiffFile{}
+	var err error
+	if oldSide != nil {
+		if oldLines, err = classifyFile(ctx, oldSide); err != nil {
+			return nil, err
+		}
+		file.Name, file.Lang = oldSide.file, oldSide.language.Name
+	}
+	if newSide != nil {
+		if newLines, err = classifyFile(ctx, newSide); err != nil {
+			return nil, err
+		}
+		file.Name, file.Lang = newSide.file, newSide.language.Name
+	}
+
+	file.Code = diffLines(oldLines.code, newLines.code)
+	file.Comments = diffLines(oldLines.comments, newLines.comments)
+	file.Blanks = diffBlanks(oldLines.blanks, newLines.blanks)
+	switch {
+	case oldSide == nil:
+		file.Status = DiffAdded
+	case newSide == nil:
+		file.Status = DiffRemoved
+	case file.Code.Same+file.Comments.Same+file.Blanks.Same == file.Code.total()+file.Comments.total()+file.Blanks.total():
+		file.Status = DiffSame
+	default:
+		file.Status = DiffModified
+	}
+	return []*ClocDiffFile{file}, nil
+}
+
+func (c DiffCount) total() int32 {
+	return c.Same + c.Modified + c.Added + c.Removed
+}
+
+// diffBlanks compares the numbers of blank lines, as blank lines are all alike.
+func diffBlanks(oldBlanks, newBlanks int32) DiffCount {
+	if oldBlanks < newBlanks {
+		return DiffCount{Same: oldBlanks, Added: newBlanks - oldBlanks}
+	}
+	return DiffCount{Same: newBlanks, Removed: oldBlanks - newBlanks}
+}
+
+// diffLines counts the lines of a that are the same, modified and removed in b, and the lines added in b.
+func diffLines(a, b []string) DiffCount {
+	var count DiffCount
+	// the common head and tail need no search
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+		count.Same++
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+		count.Same++
+	}
+
+	ops, ok := diffEdits(a, b, maxDiffEdits)
+	if !ok {
+		count.add(diffLineSets(a, b))
+		return count
+	}
+	var removed, added int32
+	flush := func() {
+		modified := removed
+		if added < modified {
+			modified = added
+		}
+		count.Modified += modified
+		count.Removed += removed - modified
+		count.Added += added - modified
+		removed, added = 0, 0
+	}
+	for _, op := range ops {
+		switch op {
+		case '=':
+			flush()
+			count.Same++
+		case '-':
+			removed++
+		case '+':
+			added++
+		}
+	}
+	flush()
+	return count
+}
+
+// diffLineSets compares a and b as multisets of lines, ignoring their order.
+func diffLineSets(a, b []string) DiffCount {
+	counts := make(map[string]int32, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	var same int32
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+			same++
+		}
+	}
+	removed, added := int32(len(a))-same, int32(len(b))-same
+	modified := removed
+	if added < modified {
+		modified = added
+	}
+	return DiffCount{Same: same, Modified: modified, Removed: removed - modified, Added: added - modified}
+}
+
+// diffEdits returns the shortest edit script from a to b with Myers' algorithm, as '=' for a
+// line kept, '-' for a line of a removed and '+' for a line of b added. It fails when more
+// than maxEdits edits are needed.
+func diffEdits(a, b []string, maxEdits int) ([]byte, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil, true
+	}
+	if maxEdits > max {
+		maxEdits = max
+	}
+
+	// v[offset+k] is the furthest x reached on the diagonal k = x - y
+	offset := maxEdits + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] is the window [-d-1, d+1] of v before the step d
+	var trace [][]int
+	for d := 0; d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackEdits(trace, n, m), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrackEdits(trace [][]int, n, m int) []byte {
+	var ops []byte
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int {
+			return v[k+d+1]
+		}
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, '=')
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, '+')
+			} else {
+				ops = append(ops, '-')
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// SortedDiffLanguages returns the languages of r sorted by name.
+func (r *DiffResult) SortedDiffLanguages() []ClocDiffLanguage {
+	langs := make([]ClocDiffLanguage, 0, len(r.Languages))
+	for _, language := range r.Languages {
+		langs = append(langs, *language)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		return langs[i].Name < langs[j].Name
+	})
+	return langs
+}
+
+// SortedDiffFiles returns the files of r sorted by their relative path, then by status.
+func (r *DiffResult) SortedDiffFiles() []ClocDiffFile {
+	files := make([]ClocDiffFile, 0, len(r.Files))
+	for _, file := range r.Files {
+		files = append(files, *file)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return lessDiffFile(&files[i], &files[j])
+	})
+	return files
+}
@@ -0,0 +1,244 @@
+package gocloc
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want DiffCount
+	}{
+		{"a b c", "a b c", DiffCount{Same: 3}},
+		{"a b c", "a x c", DiffCount{Same: 2, Modified: 1}},
+		{"a b c", "a b c d e", DiffCount{Same: 3, Added: 2}},
+		{"a b c d", "b d", DiffCount{Same: 2, Removed: 2}},
+		// a removed line before and an added line after c are not a modification
+		{"a b c", "a c d", DiffCount{Same: 2, Removed: 1, Added: 1}},
+		{"a b c", "x y", DiffCount{Modified: 2, Removed: 1}},
+		{"", "a b", DiffCount{Added: 2}},
+	}
+	for _, test := range tests {
+		got := diffLines(strings.Fields(test.a), strings.Fields(test.b))
+		if got != test.want {
+			t.Errorf("invalid logic. a=%q b=%q got=%+v want=%+v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestDiffLinesOverMaxEdits(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxDiffEdits+10; i++ {
+		a = append(a, "old", "same")
+		b = append(b, "same", "new")
+	}
+	got := diffLines(a, b)
+	want := DiffCount{Same: int32(len(a) / 2), Modified: int32(len(a) / 2)}
+	if got != want {
+		t.Errorf("invalid logic. got=%+v want=%+v", got, want)
+	}
+}
+
+// diffFileOf returns the first file of result named name.
+func diffFileOf(result *DiffResult, name string) *ClocDiffFile {
+	for _, file := range result.Files {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+func TestProcessorDiff(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	writeTestFiles(t, oldDir, map[string]string{
+		"main.go":    "package main\n\n// main\nfunc main() {\n\tprintln(1)\n}\n",
+		"same.go":    "package same\n",
+		"removed.py": "# removed\nx = 1\ny = 2\n",
+	})
+	writeTestFiles(t, newDir, map[string]string{
+		"main.go":  "package main\n\n// main\nfunc main() {\n\tprintln(2)\n}\n\nfunc f() {}\n",
+		"same.go":  "package same\n",
+		"added.rb": "# added\nputs 1\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).Diff([]string{oldDir}, []string{newDir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	mainGo := diffFileOf(result, filepath.Join(newDir, "main.go"))
+	if mainGo == nil || mainGo.Status != DiffModified {
+		t.Fatalf("invalid logic. main.go=%+v", mainGo)
+	}
+	if mainGo.Code != (DiffCount{Same: 3, Modified: 1, Added: 1}) || mainGo.Comments != (DiffCount{Same: 1}) || mainGo.Blanks != (DiffCount{Same: 1, Added: 1}) {
+		t.Errorf("invalid logic. main.go=%+v", mainGo)
+	}
+	if f := diffFileOf(result, filepath.Join(newDir, "same.go")); f == nil || f.Status != DiffSame {
+		t.Errorf("invalid logic. same.go=%+v", f)
+	}
+	if f := diffFileOf(result, filepath.Join(oldDir, "removed.py")); f == nil || f.Status != DiffRemoved || f.Code.Removed != 2 || f.Comments.Removed != 1 {
+		t.Errorf("invalid logic. removed.py=%+v", f)
+	}
+	if f := diffFileOf(result, filepath.Join(newDir, "added.rb")); f == nil || f.Status != DiffAdded || f.Code.Added != 1 {
+		t.Errorf("invalid logic. added.rb=%+v", f)
+	}
+
+	golang := result.Languages["Go"]
+	if golang.Files != (DiffCount{Same: 1, Modified: 1}) || golang.Code != (DiffCount{Same: 4, Modified: 1, Added: 1}) {
+		t.Errorf("invalid logic. Go=%+v", golang)
+	}
+	if result.Total.Files != (DiffCount{Same: 1, Modified: 1, Added: 1, Removed: 1}) {
+		t.Errorf("invalid logic. total=%+v", result.Total)
+	}
+
+	buf, err := json.Marshal(NewJSONDiffLanguagesResult(result))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	var decoded JSONDiffLanguagesResult
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(decoded.Languages) != 3 || decoded.Languages[0].Name != "Go" || decoded.Total.Code != result.Total.Code {
+		t.Errorf("invalid logic. json=%s", buf)
+	}
+}
+
+func TestProcessorDiffRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {\n}\n"})
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "v1")
+	runGit(t, dir, "tag", "v1")
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n\n// main\nfunc main() {\n\tprintln()\n}\n"})
+	runGit(t, dir, "commit", "-q", "-a", "-m", "v2")
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DiffRevisions("v1", "main", []string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	mainGo := diffFileOf(result, filepath.Join(dir, "main.go"))
+	if mainGo == nil || mainGo.Code != (DiffCount{Same: 3, Added: 1}) || mainGo.Comments != (DiffCount{Added: 1}) {
+		t.Errorf("invalid logic. main.go=%+v", mainGo)
+	}
+}
+
+func TestProcessorDiffDuplicatedFiles(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	writeTestFiles(t, oldDir, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package a\n",
+	})
+	writeTestFiles(t, newDir, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package a\n\nvar b = 1\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).Diff([]string{oldDir}, []string{newDir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if f := diffFileOf(result, filepath.Join(newDir, "b.go")); f == nil || f.Status != DiffModified {
+		t.Errorf("invalid logic. b.go=%+v", f)
+	}
+	if result.Total.Files != (DiffCount{Same: 1, Modified: 1}) {
+		t.Errorf("invalid logic. total=%+v", result.Total.Files)
+	}
+}
+
+func TestProcessorDiffRevisionsLanguageChange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeTestFiles(t, dir, map[string]string{"run": "#!/bin/bash\necho 1\n"})
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "v1")
+	runGit(t, dir, "tag", "v1")
+	writeTestFiles(t, dir, map[string]string{"run": "#!/usr/bin/env python\nprint(1)\nprint(2)\n"})
+	runGit(t, dir, "commit", "-q", "-a", "-m", "v2")
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DiffRevisions("v1", "main", []string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("invalid logic. files=%+v", result.Files)
+	}
+	removed, added := result.Files[0], result.Files[1]
+	if removed.Lang != "BASH" || removed.Status != DiffRemoved || removed.Code.Removed != 2 {
+		t.Errorf("invalid logic. removed=%+v", removed)
+	}
+	if added.Lang != "Python" || added.Status != DiffAdded || added.Code.Added != 3 {
+		t.Errorf("invalid logic. added=%+v", added)
+	}
+	if len(result.SortedDiffFiles()) != 2 {
+		t.Errorf("invalid logic. sorted=%+v", result.SortedDiffFiles())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).DiffRevisionsContext(ctx, "v1", "main", []string{dir}); err != context.Canceled {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+}
+
+func TestProcessorDiffSortsByRelativePath(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	writeTestFiles(t, oldDir, map[string]string{
+		"a.go":     "package a\n",
+		"b/b.go":   "package b\n",
+		"run":      "#!/bin/bash\necho 1\n",
+		"z/old.go": "package z\n",
+	})
+	writeTestFiles(t, newDir, map[string]string{
+		"a.go":   "package a\n\nvar a = 1\n",
+		"b/b.go": "package b\n",
+		"run":    "#!/usr/bin/env python\nprint(1)\n",
+		"c.go":   "package c\n",
+	})
+
+	result, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).Diff([]string{oldDir}, []string{newDir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	expected := []string{"a.go modified", "b/b.go same", "c.go added", "run removed", "run added", "z/old.go removed"}
+	relNames := func(files []ClocDiffFile) string {
+		var names []string
+		for _, file := range files {
+			rel, err := filepath.Rel(oldDir, file.Name)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				rel, _ = filepath.Rel(newDir, file.Name)
+			}
+			names = append(names, filepath.ToSlash(rel)+" "+file.Status)
+		}
+		return strings.Join(names, ",")
+	}
+
+	var files []ClocDiffFile
+	for _, file := range result.Files {
+		files = append(files, *file)
+	}
+	if got := relNames(files); got != strings.Join(expected, ",") {
+		t.Errorf("invalid logic. files=%v", got)
+	}
+	if got := relNames(result.SortedDiffFiles()); got != strings.Join(expected, ",") {
+		t.Errorf("invalid logic. sorted=%v", got)
+	}
+}
@@ -0,0 +1,258 @@
+package gocloc
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of code of other languages inside a file.
+const (
+	// embedTags are the <script> and <style> elements of HTML and single-file components.
+	embedTags = iota + 1
+	// embedFences are the fenced code blocks of Markdown.
+	embedFences
+)
+
+// languageEmbedding are the languages embedding other languages by language name.
+var languageEmbedding = map[string]int{
+	"HTML":     embedTags,
+	"Markdown": embedFences,
+	"Svelte":   embedTags,
+	"Vue":      embedTags,
+}
+
+// embeddedAliases are the names of code blocks and lang attributes that are neither
+// an extension nor a language name.
+var embeddedAliases = map[string]string{
+	"c++":        "C++",
+	"console":    "Bourne Shell",
+	"golang":     "Go",
+	"javascript": "JavaScript",
+	"objc":       "Objective-C",
+	"shell":      "Bourne Shell",
+	"stylus":     "Stylus",
+	"typescript": "TypeScript",
+}
+
+var (
+	reEmbeddedTag   = regexp.MustCompile(`(?i)^<(script|style)\b([^>]*)>`)
+	reEmbeddedAttr  = regexp.MustCompile(`(?i)\b(lang|type)\s*=\s*["']?([^"'\s>]+)`)
+	reEmbeddedFence = regexp.MustCompile("^(`{3,}|~{3,})\\s*([^`\\s{]*)")
+)
+
+var (
+	builtinLanguagesOnce sync.Once
+	builtinLanguages     *DefinedLanguages
+)
+
+// embeddedLanguages returns the definitions the embedded languages of opts are looked up in.
+func (opts *ClocOptions) embeddedLanguages() *DefinedLanguages {
+	if opts.EmbeddedLanguages != nil {
+		return opts.EmbeddedLanguages
+	}
+	builtinLanguagesOnce.Do(func() {
+		builtinLanguages = NewDefinedLanguages()
+	})
+	return builtinLanguages
+}
+
+// lookupEmbeddedLanguage returns the language of langs for a code block name or lang attribute, such as "ts".
+func lookupEmbeddedLanguage(langs *DefinedLanguages, name string) (*Language, bool) {
+	name = strings.ToLower(name)
+	if name == "" {
+		return nil, false
+	}
+
+	if n, ok := embeddedAliases[name]; ok {
+		name = n
+	} else if n, ok := langs.ExtLanguage(name); ok {
+		name = n
+	} else {
+		for langName := range langs.Langs {
+			if strings.EqualFold(langName, name) {
+				name = langName
+				break
+			}
+		}
+	}
+	lang, ok := langs.Langs[name]
+	return lang, ok
+}
+
+// embeddedBlock is the code of another language inside a file.
+type embeddedBlock struct {
+	language *Language
+	// closing is the line prefix that closes the block.
+	closing string
+	// file are the counts of the lines of the block so far.
+	file  *ClocFile
+	lines *lineScanner
+}
+
+// openEmbeddedBlock returns the block of another language of langs that line opens in parent.
+// The opening and closing lines belong to parent.
+func openEmbeddedBlock(langs *DefinedLanguages, parent *Language, line string, opts *ClocOptions) *embeddedBlock {
+	switch languageEmbedding[parent.Name] {
+	case embedTags:
+		m := reEmbeddedTag.FindStringSubmatch(line)
+		if m == nil {
+			return nil
+		}
+		tag := strings.ToLower(m[1])
+		if strings.Contains(strings.ToLower(line), "</"+tag) {
+			// one line element such as <script src="app.js"></script>
+			return nil
+		}
+		lang := embeddedTagLanguage(langs, tag, m[2])
+		if lang == nil {
+			return nil
+		}
+		return newEmbeddedBlock(lang, "</"+tag, opts)
+	case embedFences:
+		m := reEmbeddedFence.FindStringSubmatch(line)
+		if m == nil {
+			return nil
+		}
+		// a block without a known language is part of the document
+		lang, ok := lookupEmbeddedLanguage(langs, m[2])
+		if !ok {
+			lang = parent
+		}
+		return newEmbeddedBlock(lang, m[1], opts)
+	}
+	return nil
+}
+
+func newEmbeddedBlock(language *Language, closing string, opts *ClocOptions) *embeddedBlock {
+	return &embeddedBlock{
+		language: language,
+		closing:  closing,
+		file:     &ClocFile{Lang: language.Name},
+		lines:    newLineScanner(language, opts),
+	}
+}
+
+// embeddedTagLanguage returns the language of langs of a <script> or <style> element with attrs.
+func embeddedTagLanguage(langs *DefinedLanguages, tag, attrs string) *Language {
+	for _, m := range reEmbeddedAttr.FindAllStringSubmatch(attrs, -1) {
+		value := strings.ToLower(m[2])
+		if strings.ToLower(m[1]) == "type" {
+			switch {
+			case strings.HasSuffix(value, "typescript"):
+				value = "ts"
+			case strings.HasSuffix(value, "json"):
+				value = "json"
+			case value == "module" || strings.HasSuffix(value, "javascript"):
+				value = "js"
+			default:
+				// templates and other data blocks are not code
+				return nil
+			}
+		}
+		if lang, ok := lookupEmbeddedLanguage(langs, value); ok {
+			return lang
+		}
+	}
+
+	if tag == "style" {
+		lang, _ := lookupEmbeddedLanguage(langs, "css")
+		return lang
+	}
+	lang, _ := lookupEmbeddedLanguage(langs, "js")
+	return lang
+}
+
+// closes reports whether line closes the block.
+func (b *embeddedBlock) closes(line string) bool {
+	if strings.HasPrefix(b.closing, "</") {
+		return strings.Contains(strings.ToLower(line), b.closing)
+	}
+	return strings.HasPrefix(line, b.closing) && strings.Trim(line, b.closing[:1]) == ""
+}
+
+// addEmbeddedBlock adds the counts of block to clocFile, and to its embedded languages
+// unless the block is written in parent.
+func addEmbeddedBlock(clocFile *ClocFile, parent *Language, block *embeddedBlock) {
+	child := block.file
+	if child.Code+child.Comments+child.Blanks == 0 {
+		return
+	}
+
+	child.Name = clocFile.Name
+	clocFile.Code += child.Code
+	clocFile.Comments += child.Comments
+	clocFile.DocComments += child.DocComments
+	clocFile.Blanks += child.Blanks
+	clocFile.Mixed += child.Mixed
+	if block.language.Name != parent.Name {
+		addEmbedded(clocFile, child)
+	}
+}
+
+// addEmbedded adds the counts of child to the embedded languages of clocFile.
+func addEmbedded(clocFile *ClocFile, child *ClocFile) {
+	for i := range clocFile.Embedded {
+		if e := &clocFile.Embedded[i]; e.Lang == child.Lang {
+			e.Code += child.Code
+			e.Comments += child.Comments
+			e.DocComments += child.DocComments
+			e.Blanks += child.Blanks
+			e.Mixed += child.Mixed
+			return
+		}
+	}
+	clocFile.Embedded = append(clocFile.Embedded, ClocFile{
+		Code:        child.Code,
+		Comments:    child.Comments,
+		DocComments: child.DocComments,
+		Blanks:      child.Blanks,
+		Mixed:       child.Mixed,
+		Name:        child.Name,
+		Lang:        child.Lang,
+	})
+	sort.Slice(clocFile.Embedded, func(i, j int) bool {
+		return clocFile.Embedded[i].Lang < clocFile.Embedded[j].Lang
+	})
+}
+
+// addEmbeddedFile adds the embedded languages of file to language.
+func addEmbeddedFile(language *Language, file *ClocFile) {
+	for _, e := range file.Embedded {
+		if language.Embedded == nil {
+			language.Embedded = make(map[string]*Language)
+		}
+		child, ok := language.Embedded[e.Lang]
+		if !ok {
+			child = NewLanguage(e.Lang, []string{}, [][]string{{"", ""}})
+			language.Embedded[e.Lang] = child
+		}
+		child.Files = append(child.Files, file.Name)
+		child.Code += e.Code
+		child.Comments += e.Comments
+		child.DocComments += e.DocComments
+		child.Blanks += e.Blanks
+		child.Mixed += e.Mixed
+	}
+}
+
+// embeddedClocLanguages returns the embedded languages of language sorted by name.
+func embeddedClocLanguages(language *Language) []ClocLanguage {
+	var langs []ClocLanguage
+	for _, child := range language.Embedded {
+		langs = append(langs, ClocLanguage{
+			Name:        child.Name,
+			FilesCount:  int32(len(child.Files)),
+			Code:        child.Code,
+			Comments:    child.Comments,
+			DocComments: child.DocComments,
+			Blanks:      child.Blanks,
+			Mixed:       child.Mixed,
+		})
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		return langs[i].Name < langs[j].Name
+	})
+	return langs
+}
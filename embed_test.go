@@ -0,0 +1,205 @@
+package gocloc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnalyzeReader4HTMLWithEmbedded(t *testing.T) {
+	buf := bytes.NewBufferString(`<html>
+<head>
+<style>
+body { color: red; }
+/* comment */
+</style>
+<script type="text/javascript">
+// comment
+console.log("</style>");
+
+</script>
+<script src="app.js"></script>
+<script type="text/x-template">
+<div></div>
+</script>
+</head>
+</html>
+`)
+
+	language := NewLanguage("HTML", []string{}, [][]string{{"<!--", "-->"}})
+	clocOpts := NewClocOptions()
+	clocOpts.Embedded = true
+	clocFile := AnalyzeReader("test.html", language, buf, clocOpts)
+
+	if clocFile.Blanks != 1 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 2 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 14 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if len(clocFile.Embedded) != 2 {
+		t.Fatalf("invalid logic. embedded=%v", clocFile.Embedded)
+	}
+	if css := clocFile.Embedded[0]; css.Lang != "CSS" || css.Code != 1 || css.Comments != 1 {
+		t.Errorf("invalid logic. css=%v", css)
+	}
+	if js := clocFile.Embedded[1]; js.Lang != "JavaScript" || js.Code != 1 || js.Comments != 1 || js.Blanks != 1 {
+		t.Errorf("invalid logic. js=%v", js)
+	}
+}
+
+func TestAnalyzeReader4VueWithEmbedded(t *testing.T) {
+	buf := bytes.NewBufferString(`<template>
+  <div>{{ msg }}</div>
+</template>
+<script lang="ts">
+const msg: string = "hi"
+</script>
+<style lang="scss">
+.a { .b { color: red; } }
+</style>
+`)
+
+	language := NewLanguage("Vue", []string{"//"}, [][]string{{"/*", "*/"}, {"<!--", "-->"}})
+	clocOpts := NewClocOptions()
+	clocOpts.Embedded = true
+	clocFile := AnalyzeReader("test.vue", language, buf, clocOpts)
+
+	if clocFile.Code != 9 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if len(clocFile.Embedded) != 2 {
+		t.Fatalf("invalid logic. embedded=%v", clocFile.Embedded)
+	}
+	if clocFile.Embedded[0].Lang != "Sass" || clocFile.Embedded[1].Lang != "TypeScript" {
+		t.Errorf("invalid logic. embedded=%v", clocFile.Embedded)
+	}
+}
+
+func TestAnalyzeReader4MarkdownWithEmbedded(t *testing.T) {
+	content := "# Title\n\n```go\n// comment\npackage main\n```\n\n~~~python\nx = 1\n```\n~~~\n\n```\nplain\n```\n"
+
+	language := NewLanguage("Markdown", []string{}, [][]string{{"", ""}})
+	clocOpts := NewClocOptions()
+	clocOpts.Embedded = true
+	clocFile := AnalyzeReader("test.md", language, bytes.NewBufferString(content), clocOpts)
+
+	if clocFile.Blanks != 3 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 1 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 11 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if len(clocFile.Embedded) != 2 {
+		t.Fatalf("invalid logic. embedded=%v", clocFile.Embedded)
+	}
+	if goFile := clocFile.Embedded[0]; goFile.Lang != "Go" || goFile.Code != 1 || goFile.Comments != 1 {
+		t.Errorf("invalid logic. go=%v", goFile)
+	}
+	// the ``` line inside the ~~~ block is python code
+	if py := clocFile.Embedded[1]; py.Lang != "Python" || py.Code != 2 {
+		t.Errorf("invalid logic. python=%v", py)
+	}
+
+	// without the option, the file is counted as one language
+	clocFile = AnalyzeReader("test.md", language, bytes.NewBufferString(content), NewClocOptions())
+	if clocFile.Code != 12 || len(clocFile.Embedded) != 0 {
+		t.Errorf("invalid logic. code=%v embedded=%v", clocFile.Code, clocFile.Embedded)
+	}
+}
+
+func TestAnalyzeEmbeddedLanguages(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a.html": "<script>\nvar a = 1;\n</script>\n",
+		"b.html": "<script>\nvar b = 2;\nvar c = 3;\n</script>\n",
+	})
+
+	opts := NewClocOptions()
+	opts.Embedded = true
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	html := result.Languages["HTML"]
+	js, ok := html.Embedded["JavaScript"]
+	if !ok {
+		t.Fatalf("invalid logic. embedded=%v", html.Embedded)
+	}
+	if len(js.Files) != 2 || js.Code != 3 {
+		t.Errorf("invalid logic. files=%v code=%v", js.Files, js.Code)
+	}
+	if html.Code != 7 || result.Total.Code != 7 {
+		t.Errorf("invalid logic. code=%v total=%v", html.Code, result.Total.Code)
+	}
+
+	langs := NewJSONLanguagesResultFromCloc(result.Total, Languages{*html}).Languages
+	if len(langs[0].Embedded) != 1 || langs[0].Embedded[0].FilesCount != 2 {
+		t.Errorf("invalid logic. embedded=%v", langs[0].Embedded)
+	}
+}
+
+func TestAnalyzeEmbeddedLanguagesOfProcessor(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a.md": "```go\n# note\nx\n```\n",
+	})
+
+	langs := NewDefinedLanguages()
+	langs.Langs["Go"] = NewLanguage("Go", []string{"#"}, [][]string{{"", ""}})
+	opts := NewClocOptions()
+	opts.Embedded = true
+	result, err := NewProcessor(langs, opts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	// the block is counted by the Go of the processor, not the built-in one
+	goLang, ok := result.Languages["Markdown"].Embedded["Go"]
+	if !ok {
+		t.Fatalf("invalid logic. embedded=%v", result.Languages["Markdown"].Embedded)
+	}
+	if goLang.Comments != 1 || goLang.Code != 1 {
+		t.Errorf("invalid logic. comments=%v code=%v", goLang.Comments, goLang.Code)
+	}
+	if opts.EmbeddedLanguages != nil {
+		t.Errorf("invalid logic. options are changed")
+	}
+}
+
+func TestAnalyzeReaderEmbeddedCallbackOrder(t *testing.T) {
+	content := "<div>\n<script>\n// comment\nvar a = 1;\n\n</script>\n</div>\n"
+
+	var lines []string
+	language := NewLanguage("HTML", []string{}, [][]string{{"<!--", "-->"}})
+	clocOpts := NewClocOptions()
+	clocOpts.Embedded = true
+	clocOpts.OnCode = func(line string) { lines = append(lines, "code:"+line) }
+	clocOpts.OnComment = func(line string) { lines = append(lines, "comment:"+line) }
+	clocOpts.OnBlank = func(line string) { lines = append(lines, "blank:"+line) }
+	AnalyzeReader("test.html", language, bytes.NewBufferString(content), clocOpts)
+
+	expected := []string{
+		"code:<div>",
+		"code:<script>",
+		"comment:// comment",
+		"code:var a = 1;",
+		"blank:",
+		"code:</script>",
+		"code:</div>",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("invalid logic. lines=%q", lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("invalid logic. lines[%d]=%q, expected %q", i, lines[i], expected[i])
+		}
+	}
+}
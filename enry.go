@@ -0,0 +1,174 @@
+package gocloc
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-enry/go-enry/v2"
+	"github.com/go-enry/go-enry/v2/data"
+	"github.com/spf13/afero"
+)
+
+// enryReadSize is the size of the head of a file given to the enry classifier.
+const enryReadSize = 64 * 1024
+
+// generatedCheck is a content rule of enry for generated files.
+type generatedCheck struct {
+	// suffixes are the endings of the lowercase paths the rule applies to.
+	suffixes []string
+	// lines is the number of lines matched by re, at the head of the file, or at its end when
+	// negative. 0 matches the whole content.
+	lines int
+	re    *regexp.Regexp
+}
+
+// generatedChecks are the content rules of enry for generated files, without the rule for
+// minified files, which are the separate category of ClocOptions.SkipMinified.
+var generatedChecks = []generatedCheck{
+	// source map reference
+	{[]string{".js", ".css"}, -2, regexp.MustCompile(`(?m)^/[*/][#@] source(?:Mapping)?URL|sourceURL=`)},
+	// source map
+	{[]string{".js.map", ".css.map"}, 1, regexp.MustCompile(`^(?:\{"version":\d+,|/\*\* Begin line maps\. \*\*/\{)`)},
+	// compiled CoffeeScript
+	{[]string{".js"}, 0, regexp.MustCompile(`\A\(function\(\) \{\n(?s:.*)\b(?:__bind|__extends|__hasProp|__indexOf|__slice)\b(?s:.*)\n\}\)\.call\(this\);\n*\z`)},
+	// .NET XML documentation
+	{[]string{".xml"}, 3, regexp.MustCompile(`\A.*\n.*<doc>.*\n.*<assembly>`)},
+	{[]string{".js"}, 5, regexp.MustCompile(`Generated by PEG\.js`)},
+	// PostScript and PostScript fonts
+	{[]string{".ps", ".eps", ".pfa"}, 10, regexp.MustCompile(`(?m)^%%Creator: .*(?:[0-9]|draw|mpage|ImageMagick|inkscape|MATLAB|PCBNEW|pnmtops|\(Unknown\)|Serif Affinity|Filterimage -tops|EAGLE)`)},
+	{[]string{".ps", ".eps", ".pfa"}, 0, regexp.MustCompile(`[\r\n]\s*(?:currentfile eexec\s+|/sfnts\s+\[)`)},
+	{[]string{".go"}, 40, regexp.MustCompile(`Code generated by`)},
+	{[]string{".py", ".java", ".h", ".cc", ".cpp", ".m", ".rb", ".php"}, 3, regexp.MustCompile(`Generated by the protocol buffer compiler\.  DO NOT EDIT!`)},
+	{[]string{".js"}, 6, regexp.MustCompile(`GENERATED CODE -- DO NOT EDIT!`)},
+	{[]string{".rb", ".py", ".go", ".js", ".m", ".java", ".h", ".cc", ".cpp", ".php"}, 6, regexp.MustCompile(`Autogenerated by Thrift Compiler`)},
+	// JNI header
+	{[]string{".h"}, 2, regexp.MustCompile(`DO NOT EDIT THIS FILE - it is machine generated \*/.*\n.*#include <jni\.h>`)},
+	// VCR cassette
+	{[]string{".yml"}, -2, regexp.MustCompile(`recorded_with: VCR`)},
+	{[]string{".c", ".cpp"}, 1, regexp.MustCompile(`Generated by Cython`)},
+	// KiCad and gfortran modules
+	{[]string{".mod"}, 1, regexp.MustCompile(`PCBNEW-LibModule-V|GFORTRAN module version '`)},
+	// Unity3D meta
+	{[]string{".meta"}, 1, regexp.MustCompile(`fileFormatVersion: `)},
+	{[]string{".rb"}, 3, regexp.MustCompile(`(?m)^# This file is automatically generated by Racc`)},
+	{[]string{".java"}, 1, regexp.MustCompile(`^/\* The following code was generated by JFlex `)},
+	// GrammarKit
+	{[]string{".java"}, 1, regexp.MustCompile(`// This is a generated file\. Not intended for manual editing\.`)},
+	{[]string{".rd"}, 1, regexp.MustCompile(`% Generated by roxygen2: do not edit by hand`)},
+	{[]string{".js"}, 1, regexp.MustCompile(`/\* (?:parser generated by jison|generated by jison-lex) `)},
+	{[]string{".cpp", ".hpp", ".h", ".cc"}, 1, regexp.MustCompile(`// Generated by the gRPC`)},
+	{[]string{".dart"}, 1, regexp.MustCompile(`(?i)generated code\W{2,3}do not modify`)},
+	{[]string{"ppport.h"}, 10, regexp.MustCompile(`Automatically created by Devel::PPPort`)},
+	// GameMaker Studio
+	{[]string{".yy", ".yyp"}, 3, regexp.MustCompile(`\A\d\.\d\.\d.+\|\{|"modelName":\s*"GM`)},
+	// GIMP image dumps
+	{[]string{".c", ".h"}, 1, regexp.MustCompile(`/\* GIMP [a-zA-Z0-9\- ]+ C\-Source image dump \(.+?\.c\) \*/|/\*  GIMP header image file format \([a-zA-Z0-9\- ]+\): .+?\.h  \*/`)},
+	// Visual Studio 6
+	{[]string{".dsp"}, 3, regexp.MustCompile(`# Microsoft Developer Studio Generated Build File`)},
+	{[]string{".js", ".py", ".lua", ".cpp", ".h", ".java", ".cs", ".php"}, 3, regexp.MustCompile(`Generated by Haxe`)},
+	// pkgdown, mandoc, Doxygen and the HTML converters of documentation
+	{[]string{".html", ".htm", ".xhtml"}, 30, regexp.MustCompile(`<!-- Generated by pkgdown: do not edit by hand -->|<!-- This is an automatically generated file\.|<!--\s+Generated by Doxygen\s+[.0-9]+\s*-->|(?i:<meta\s+name=["']?generator["']?\s+content=["']?[^"'>]*(?:latex2html|groff|makeinfo|texi2html|ronn|org\s+mode))`)},
+	{[]string{".java"}, 2, regexp.MustCompile(`This file is generated by jOOQ\.`)},
+}
+
+// match reports whether the lines of content checked by c match, path is lowercase.
+func (c *generatedCheck) match(path string, content []byte) bool {
+	applies := false
+	for _, suffix := range c.suffixes {
+		if strings.HasSuffix(path, suffix) {
+			applies = true
+			break
+		}
+	}
+	if !applies {
+		return false
+	}
+
+	switch {
+	case c.lines > 0:
+		lines := bytes.SplitN(content, []byte("\n"), c.lines+1)
+		if len(lines) > c.lines {
+			lines = lines[:c.lines]
+		}
+		content = bytes.Join(lines, []byte("\n"))
+	case c.lines < 0:
+		lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+		if len(lines) > -c.lines {
+			lines = lines[len(lines)+c.lines:]
+		}
+		content = bytes.Join(lines, []byte("\n"))
+	}
+	return c.re.Match(content)
+}
+
+// enryGenerated reports whether path is generated by the enry rules, like enry.IsGenerated
+// but without the minified files.
+func enryGenerated(path string, content []byte) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if _, ok := data.GeneratedCodeExtensions[ext]; ok {
+		return true
+	}
+	for _, m := range data.GeneratedCodeNameMatchers {
+		if m(path) {
+			return true
+		}
+	}
+	path = strings.ToLower(path)
+	for i := range generatedChecks {
+		if generatedChecks[i].match(path, content) {
+			return true
+		}
+	}
+	return false
+}
+
+// enryVendored reports whether path is a vendored file by the enry rules.
+func enryVendored(path string) bool {
+	return enry.IsVendor(path)
+}
+
+// detectByEnry classifies path with enry and returns the name of the defined language
+// with the enry strategy that decided it.
+func detectByEnry(fs afero.Fs, path string, langs *DefinedLanguages) (name, strategy string, ok bool) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return "", "", false
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, enryReadSize))
+	if err != nil {
+		return "", "", false
+	}
+
+	if lang, safe := enry.GetLanguageByFilename(path); safe {
+		if name, ok := fromEnryLanguage(lang, path, langs); ok {
+			return name, "enry:filename", true
+		}
+	}
+	if lang, safe := enry.GetLanguageByShebang(content); safe {
+		if name, ok := fromEnryLanguage(lang, path, langs); ok {
+			return name, "enry:shebang", true
+		}
+	}
+	if lang, safe := enry.GetLanguageByModeline(content); safe {
+		if name, ok := fromEnryLanguage(lang, path, langs); ok {
+			return name, "enry:modeline", true
+		}
+	}
+
+	candidates := enry.GetLanguagesByExtension(path, content, nil)
+	if len(candidates) == 1 {
+		if name, ok := fromEnryLanguage(candidates[0], path, langs); ok {
+			return name, "enry:extension", true
+		}
+	}
+	if lang, _ := enry.GetLanguageByClassifier(content, candidates); lang != "" {
+		if name, ok := fromEnryLanguage(lang, path, langs); ok {
+			return name, "enry:classifier", true
+		}
+	}
+	return "", "", false
+}
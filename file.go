@@ -2,20 +2,25 @@ package gocloc
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"unicode"
 )
 
 // ClocFile is collecting to line count result.
 type ClocFile struct {
-	Code     int32  `xml:"code,attr" json:"code"`
-	Comments int32  `xml:"comment,attr" json:"comment"`
-	Blanks   int32  `xml:"blank,attr" json:"blank"`
-	Name     string `xml:"name,attr" json:"name"`
-	Lang     string `xml:"language,attr" json:"language"`
+	Code        int32  `xml:"code,attr" json:"code"`
+	Comments    int32  `xml:"comment,attr" json:"comment"`
+	DocComments int32  `xml:"doc_comment,attr,omitempty" json:"doc_comment,omitempty"`
+	Blanks      int32  `xml:"blank,attr" json:"blank"`
+	Mixed       int32  `xml:"mixed,attr,omitempty" json:"mixed,omitempty"`
+	Name        string `xml:"name,attr" json:"name"`
+	Lang        string `xml:"language,attr" json:"language"`
+	// Embedded are the counts of the code of other languages inside the file, such as
+	// the <script> of HTML, with ClocOptions.Embedded. They are included in the counts of the file.
+	Embedded ClocFiles `xml:"embedded,omitempty" json:"embedded,omitempty"`
 }
 
 // ClocFiles is gocloc result set.
@@ -36,18 +41,34 @@ func (cf ClocFiles) Less(i, j int) bool {
 
 // AnalyzeFile is analyzing file, this function calls AnalyzeReader() inside.
 func AnalyzeFile(filename string, language *Language, opts *ClocOptions) *ClocFile {
-	fp, err := os.Open(filename)
+	clocFile, _ := analyzeFile(context.Background(), filename, language, opts)
+	return clocFile
+}
+
+func analyzeFile(ctx context.Context, filename string, language *Language, opts *ClocOptions) (*ClocFile, error) {
+	if err := ctx.Err(); err != nil {
+		return &ClocFile{Name: filename}, err
+	}
+
+	fp, err := opts.fs().Open(filename)
 	if err != nil {
 		// ignore error
-		return &ClocFile{Name: filename}
+		return &ClocFile{Name: filename}, nil
 	}
 	defer fp.Close()
 
-	return AnalyzeReader(filename, language, fp, opts)
+	return AnalyzeReaderContext(ctx, filename, language, fp, opts)
 }
 
 // AnalyzeReader is analyzing file for io.Reader.
 func AnalyzeReader(filename string, language *Language, file io.Reader, opts *ClocOptions) *ClocFile {
+	clocFile, _ := AnalyzeReaderContext(context.Background(), filename, language, file, opts)
+	return clocFile
+}
+
+// AnalyzeReaderContext is like AnalyzeReader, but checks ctx between lines.
+// When ctx is done, it returns the counts of the lines read so far together with ctx.Err().
+func AnalyzeReaderContext(ctx context.Context, filename string, language *Language, file io.Reader, opts *ClocOptions) (*ClocFile, error) {
 	if opts.Debug {
 		fmt.Printf("filename=%v\n", filename)
 	}
@@ -57,105 +78,266 @@ func AnalyzeReader(filename string, language *Language, file io.Reader, opts *Cl
 		Lang: language.Name,
 	}
 
-	isFirstLine := true
-	inComments := [][2]string{}
+	lines := newLineScanner(language, opts)
+	// block is the code of another language being read, with opts.Embedded.
+	var block *embeddedBlock
 	buf := getByteSlice()
 	defer putByteSlice(buf)
 	scanner := bufio.NewScanner(file)
 	scanner.Buffer(buf.Bytes(), 1024*1024)
 
-scannerloop:
+	done := ctx.Done()
+
 	for scanner.Scan() {
+		select {
+		case <-done:
+			if block != nil {
+				addEmbeddedBlock(clocFile, language, block)
+			}
+			return clocFile, ctx.Err()
+		default:
+		}
+
 		lineOrg := scanner.Text()
 		line := strings.TrimSpace(lineOrg)
 
-		if len(strings.TrimSpace(line)) == 0 {
-			onBlank(clocFile, opts, len(inComments) > 0, line, lineOrg)
-			continue
+		if block != nil {
+			if !block.closes(line) {
+				block.lines.scan(block.file, line, lineOrg)
+				continue
+			}
+			addEmbeddedBlock(clocFile, language, block)
+			block = nil
+		} else if opts.Embedded && lines.outside() {
+			block = openEmbeddedBlock(opts.embeddedLanguages(), language, line, opts)
 		}
 
-		// shebang line is 'code'
-		if isFirstLine && strings.HasPrefix(line, "#!") {
-			onCode(clocFile, opts, len(inComments) > 0, line, lineOrg)
-			isFirstLine = false
-			continue
+		lines.scan(clocFile, line, lineOrg)
+	}
+
+	if block != nil {
+		// unterminated block
+		addEmbeddedBlock(clocFile, language, block)
+	}
+
+	return clocFile, nil
+}
+
+// lineScanner classifies the lines of a file of language one after another.
+type lineScanner struct {
+	language    *Language
+	opts        *ClocOptions
+	isFirstLine bool
+	inComments  [][2]string
+	// inString is the multi-line string literal continuing from the previous line.
+	inString *StringLiteral
+	// inDoc is set while the multi-line comment is a documentation block.
+	inDoc bool
+	// pendingDocs are the comment lines waiting for a declaration that makes them documentation.
+	pendingDocs int32
+}
+
+func newLineScanner(language *Language, opts *ClocOptions) *lineScanner {
+	return &lineScanner{
+		language:    language,
+		opts:        opts,
+		isFirstLine: true,
+		inComments:  [][2]string{},
+	}
+}
+
+// outside reports whether the next line starts outside of a comment and a string literal.
+func (s *lineScanner) outside() bool {
+	return len(s.inComments) == 0 && s.inString == nil
+}
+
+// scan counts line in clocFile and calls the callback of its kind.
+func (s *lineScanner) scan(clocFile *ClocFile, line, lineOrg string) {
+	if len(strings.TrimSpace(line)) == 0 {
+		if len(s.inComments) == 0 {
+			s.pendingDocs = 0
 		}
+		onBlank(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
+		return
+	}
 
-		if len(inComments) == 0 {
-			if isFirstLine {
-				line = trimBOM(line)
-			}
+	// shebang line is 'code'
+	if s.isFirstLine && strings.HasPrefix(line, "#!") {
+		s.onCodeText(line)
+		onCode(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
+		s.isFirstLine = false
+		return
+	}
 
-		singleloop:
-			for _, singleComment := range language.lineComments {
-				if strings.HasPrefix(line, singleComment) {
-					// check if single comment is a prefix of multi comment
-					for _, ml := range language.multiLines {
-						if ml[0] != "" && strings.HasPrefix(line, ml[0]) {
-							break singleloop
-						}
+	if len(s.inComments) == 0 && s.inString == nil {
+		if s.isFirstLine {
+			line = trimBOM(line)
+		}
+
+	singleloop:
+		for _, singleComment := range s.language.lineComments {
+			if strings.HasPrefix(line, singleComment) {
+				// check if single comment is a prefix of multi comment
+				for _, ml := range s.language.multiLines {
+					if ml[0] != "" && strings.HasPrefix(line, ml[0]) {
+						break singleloop
 					}
-					onComment(clocFile, opts, len(inComments) > 0, line, lineOrg)
-					continue scannerloop
 				}
-			}
-
-			if len(language.multiLines) == 0 {
-				onCode(clocFile, opts, len(inComments) > 0, line, lineOrg)
-				continue scannerloop
+				countDocComment(clocFile, s.language, s.language.docs.isDocLine(line), &s.pendingDocs)
+				onComment(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
+				return
 			}
 		}
+	}
 
-		if len(inComments) == 0 && !containsComment(line, language.multiLines) {
-			onCode(clocFile, opts, len(inComments) > 0, line, lineOrg)
-			continue scannerloop
-		}
+	if len(s.inComments) == 0 && s.inString == nil && !needsScan(line, s.language) {
+		countDeclaration(clocFile, s.language, line, &s.pendingDocs)
+		s.onCodeText(line)
+		onCode(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
+		return
+	}
 
-		lenLine := len(line)
-		if len(language.multiLines) == 1 && len(language.multiLines[0]) == 2 && language.multiLines[0][0] == "" {
-			onCode(clocFile, opts, len(inComments) > 0, line, lineOrg)
+	lenLine := len(line)
+	// the continuation of a string literal is code
+	isCode := s.inString != nil
+	hasComment := len(s.inComments) > 0
+	isDoc := s.inDoc
+	// code is the line without its comments, kept for opts.onCodeText
+	keepCode := s.opts.onCodeText != nil
+	var code []byte
+scanloop:
+	for pos := 0; pos < lenLine; {
+		if s.inString != nil {
+			from := pos
+			switch {
+			case s.inString.Escape != "" && strings.HasPrefix(line[pos:], s.inString.Escape):
+				pos += len(s.inString.Escape) + 1
+			case strings.HasPrefix(line[pos:], s.inString.End):
+				pos += len(s.inString.End)
+				s.inString = nil
+			default:
+				pos++
+			}
+			if keepCode {
+				if pos > lenLine {
+					pos = lenLine
+				}
+				code = append(code, line[from:pos]...)
+			}
 			continue
 		}
-		codeFlags := make([]bool, len(language.multiLines))
-		for pos := 0; pos < lenLine; {
-			for idx, ml := range language.multiLines {
-				begin, end := ml[0], ml[1]
-				lenBegin := len(begin)
-
-				if pos+lenBegin <= lenLine && strings.HasPrefix(line[pos:], begin) && (begin != end || len(inComments) == 0) {
-					pos += lenBegin
-					inComments = append(inComments, [2]string{begin, end})
-					continue
-				}
 
-				if n := len(inComments); n > 0 {
-					last := inComments[n-1]
-					if pos+len(last[1]) <= lenLine && strings.HasPrefix(line[pos:], last[1]) {
-						inComments = inComments[:n-1]
-						pos += len(last[1])
-					}
-				} else if pos < lenLine && !unicode.IsSpace(nextRune(line[pos:])) {
-					codeFlags[idx] = true
-				}
+		if n := len(s.inComments); n > 0 {
+			last := s.inComments[n-1]
+			// only a nestable comment opens an inner level of itself
+			if s.language.isNestable(last[0]) && strings.HasPrefix(line[pos:], last[0]) {
+				pos += len(last[0])
+				s.inComments = append(s.inComments, last)
+				continue
+			}
+			if strings.HasPrefix(line[pos:], last[1]) {
+				s.inComments = s.inComments[:n-1]
+				pos += len(last[1])
+				continue
 			}
 			pos++
+			continue
 		}
 
-		isCode := true
-		for _, b := range codeFlags {
-			if !b {
-				isCode = false
+		// a delimiter of both a comment and a string, such as Python's """, starts a comment
+		// at the beginning of the line and a string after code
+		if !isCode {
+			if begin, end, ok := matchMultiLineBegin(line[pos:], s.language.multiLines); ok {
+				// an empty comment such as /**/ is not documentation
+				s.inDoc = s.language.docs.isDocBlock(line[pos:]) && !strings.HasPrefix(line[pos+len(begin):], end)
+				isDoc = isDoc || s.inDoc
+				pos += len(begin)
+				s.inComments = append(s.inComments, [2]string{begin, end})
+				hasComment = true
+				if keepCode {
+					code = append(code, ' ')
+				}
+				continue
+			}
+		}
+		if sl := matchStringLiteral(line[pos:], s.language.stringLiterals); sl != nil {
+			if keepCode {
+				code = append(code, sl.Begin...)
+			}
+			pos += len(sl.Begin)
+			s.inString = sl
+			isCode = true
+			continue
+		}
+		if begin, end, ok := matchMultiLineBegin(line[pos:], s.language.multiLines); ok {
+			pos += len(begin)
+			s.inComments = append(s.inComments, [2]string{begin, end})
+			hasComment = true
+			if keepCode {
+				code = append(code, ' ')
 			}
+			continue
+		}
+		if matchTrailingComment(line, pos, s.language) {
+			// the rest of the line is a comment
+			hasComment = true
+			break scanloop
+		}
+		if !unicode.IsSpace(nextRune(line[pos:])) {
+			isCode = true
 		}
+		if keepCode {
+			code = append(code, line[pos])
+		}
+		pos++
+	}
+	if s.inString != nil && !s.inString.MultiLine {
+		// unterminated single line literal
+		s.inString = nil
+	}
+	if len(s.inComments) == 0 {
+		s.inDoc = false
+	}
 
-		if isCode {
-			onCode(clocFile, opts, len(inComments) > 0, line, lineOrg)
-		} else {
-			onComment(clocFile, opts, len(inComments) > 0, line, lineOrg)
+	if isCode {
+		if hasComment {
+			clocFile.Mixed++
 		}
+		countDeclaration(clocFile, s.language, line, &s.pendingDocs)
+		if keepCode {
+			s.opts.onCodeText(string(code))
+		}
+		onCode(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
+	} else {
+		countDocComment(clocFile, s.language, isDoc, &s.pendingDocs)
+		onComment(clocFile, s.opts, len(s.inComments) > 0, line, lineOrg)
 	}
+}
 
-	return clocFile
+// onCodeText passes a code line without comments to opts.onCodeText.
+func (s *lineScanner) onCodeText(line string) {
+	if s.opts.onCodeText != nil {
+		s.opts.onCodeText(line)
+	}
+}
+
+// countDocComment counts a comment line as documentation. Other comment lines of a language
+// with declaration rule wait in pending for the next code line.
+func countDocComment(clocFile *ClocFile, language *Language, isDoc bool, pending *int32) {
+	switch {
+	case isDoc:
+		clocFile.DocComments++
+	case language.docs.Declaration != nil:
+		*pending++
+	}
+}
+
+// countDeclaration counts the pending comment lines as documentation when line is a documented declaration.
+func countDeclaration(clocFile *ClocFile, language *Language, line string, pending *int32) {
+	if *pending > 0 && language.docs.Declaration.MatchString(line) {
+		clocFile.DocComments += *pending
+	}
+	*pending = 0
 }
 
 func onBlank(clocFile *ClocFile, opts *ClocOptions, isInComments bool, line, lineOrg string) {
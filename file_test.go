@@ -2,6 +2,7 @@ package gocloc
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"testing"
 )
@@ -374,6 +375,285 @@ func TestAnalyzeFile4JavaWithCommentInCodeLine(t *testing.T) {
 	}
 }
 
+func TestAnalyzeReader4GoWithCommentInString(t *testing.T) {
+	buf := bytes.NewBufferString(`package main
+
+var s = "/*" // not a comment start
+var r = '"'
+
+/* comment */
+var raw = ` + "`" + `
+/* still a string
+// also a string
+` + "`" + `
+var escaped = "\" /*" + "*/"
+func main() {}
+`)
+
+	language := NewDefinedLanguages().Langs["Go"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.go", language, buf, clocOpts)
+
+	if clocFile.Blanks != 2 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 1 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 9 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4PythonWithTripleQuoteString(t *testing.T) {
+	buf := bytes.NewBufferString(`def f():
+    """docstring
+    """
+    s = """
+# not a comment
+"""
+    t = "'''"
+    # comment
+    return s
+`)
+
+	language := NewDefinedLanguages().Langs["Python"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.py", language, buf, clocOpts)
+
+	if clocFile.Blanks != 0 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 3 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 6 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4RustWithRawString(t *testing.T) {
+	buf := bytes.NewBufferString(`fn main() {
+    let a = r#"a "quoted" /* text"#;
+    let b = r"*/";
+    let c = "multi
+/* line";
+    let d = '"';
+    /* comment */
+}
+`)
+
+	language := NewDefinedLanguages().Langs["Rust"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.rs", language, buf, clocOpts)
+
+	if clocFile.Blanks != 0 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 1 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 7 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4GoWithTrailingComment(t *testing.T) {
+	buf := bytes.NewBufferString(`package main // package comment
+
+// comment
+var a = 1
+var b = "// not a comment"
+var c = 2 /* block */
+/* block */ var d = 3
+var e = 4 /* open
+close */
+`)
+
+	language := NewDefinedLanguages().Langs["Go"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.go", language, buf, clocOpts)
+
+	if clocFile.Blanks != 1 {
+		t.Errorf("invalid logic. blanks=%v", clocFile.Blanks)
+	}
+	if clocFile.Comments != 2 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 6 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if clocFile.Mixed != 4 {
+		t.Errorf("invalid logic. mixed=%v", clocFile.Mixed)
+	}
+}
+
+func TestAnalyzeReader4GoDocComments(t *testing.T) {
+	buf := bytes.NewBufferString(`// Package main is documented.
+package main
+
+// Exported is documented.
+// It has two lines.
+func Exported() {}
+
+// unexported is not documented.
+func unexported() {}
+
+// detached comment
+
+type T struct {
+	// Field is documented.
+	Field int
+}
+
+/*
+Method is documented.
+*/
+func (t *T) Method() {}
+`)
+
+	language := NewDefinedLanguages().Langs["Go"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.go", language, buf, clocOpts)
+
+	if clocFile.Comments != 9 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.DocComments != 7 {
+		t.Errorf("invalid logic. doc comments=%v", clocFile.DocComments)
+	}
+}
+
+func TestAnalyzeReader4RustDocComments(t *testing.T) {
+	buf := bytes.NewBufferString(`//! Crate documentation.
+
+/// Documented.
+//// Not documented.
+// Not documented.
+fn f() {}
+
+/** Documented
+ * block. */
+/*** Not documented. */
+/**/
+`)
+
+	language := NewDefinedLanguages().Langs["Rust"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.rs", language, buf, clocOpts)
+
+	if clocFile.Comments != 8 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.DocComments != 4 {
+		t.Errorf("invalid logic. doc comments=%v", clocFile.DocComments)
+	}
+}
+
+func TestAnalyzeReader4JavaDocComments(t *testing.T) {
+	buf := bytes.NewBufferString(`/**
+ * Javadoc.
+ */
+public class A {
+    /* not documented */
+    int a; /** trailing */
+    // not documented
+}
+`)
+
+	language := NewDefinedLanguages().Langs["Java"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("A.java", language, buf, clocOpts)
+
+	if clocFile.Comments != 5 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.DocComments != 3 {
+		t.Errorf("invalid logic. doc comments=%v", clocFile.DocComments)
+	}
+}
+
+func TestAnalyzeReader4PythonDocComments(t *testing.T) {
+	buf := bytes.NewBufferString(`def f():
+    """Docstring
+    of f.
+    """
+    # comment
+    return 1
+`)
+
+	language := NewDefinedLanguages().Langs["Python"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.py", language, buf, clocOpts)
+
+	if clocFile.Comments != 4 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.DocComments != 3 {
+		t.Errorf("invalid logic. doc comments=%v", clocFile.DocComments)
+	}
+}
+
+func TestAnalyzeReader4CWithNonNestedComment(t *testing.T) {
+	buf := bytes.NewBufferString(`/* outer /* inner */
+int a;
+/*
+/* still one comment
+*/
+int b;
+`)
+
+	language := NewDefinedLanguages().Langs["C"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.c", language, buf, clocOpts)
+
+	if clocFile.Comments != 4 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 2 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4RustWithNestedComment(t *testing.T) {
+	buf := bytes.NewBufferString(`/* outer /* inner */
+still a comment */
+fn main() {}
+`)
+
+	language := NewDefinedLanguages().Langs["Rust"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.rs", language, buf, clocOpts)
+
+	if clocFile.Comments != 2 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 1 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4DWithNestedAndNonNestedComments(t *testing.T) {
+	buf := bytes.NewBufferString(`/+ outer /+ inner +/
+/* ignored in a nested comment
++/
+int a;
+/* no /+ nesting here */
+int b;
+`)
+
+	language := NewDefinedLanguages().Langs["D"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.d", language, buf, clocOpts)
+
+	if clocFile.Comments != 4 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Code != 2 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
 func TestAnalyzeFile4Makefile(t *testing.T) {
 	tmpfile, err := os.CreateTemp("", "Makefile.am")
 	if err != nil {
@@ -528,3 +808,101 @@ const color = "blue"
 		t.Errorf("invalid logic. lang=%v", clocFile.Lang)
 	}
 }
+
+func TestAnalyzeReaderContextCanceled(t *testing.T) {
+	buf := bytes.NewBuffer([]byte(`a = 1
+b = 2
+c = 3
+d = 4
+e = 5
+`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	language := NewDefinedLanguages().Langs["Python"]
+	clocOpts := NewClocOptions()
+	clocOpts.OnCode = func(line string) {
+		if line == "b = 2" {
+			cancel()
+		}
+	}
+	clocFile, err := AnalyzeReaderContext(ctx, "test.py", language, buf, clocOpts)
+	if err != context.Canceled {
+		t.Errorf("invalid logic. err=%v", err)
+	}
+	if clocFile.Code != 2 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+}
+
+func TestAnalyzeReader4RustLifetimes(t *testing.T) {
+	buf := bytes.NewBufferString(`fn f(x: &'a str) { /* start
+ comment
+ more */
+}
+fn g<'a>(s: &'a str) -> &'a str { s } // c
+let c = '"'; // quote
+let e = '\''; /* escaped */
+`)
+
+	language := NewDefinedLanguages().Langs["Rust"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.rs", language, buf, clocOpts)
+
+	if clocFile.Code != 5 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if clocFile.Comments != 2 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Mixed != 4 {
+		t.Errorf("invalid logic. mixed=%v", clocFile.Mixed)
+	}
+}
+
+func TestAnalyzeReader4ShellMixed(t *testing.T) {
+	buf := bytes.NewBufferString(`#!/bin/sh
+# comment
+x=1 # note
+echo "a # b"
+echo 'c # d' # e
+echo $# a#b
+echo it's # f
+`)
+
+	language := NewDefinedLanguages().Langs["BASH"]
+	clocOpts := NewClocOptions()
+	clocFile := AnalyzeReader("test.sh", language, buf, clocOpts)
+
+	if clocFile.Code != 6 {
+		t.Errorf("invalid logic. code=%v", clocFile.Code)
+	}
+	if clocFile.Comments != 1 {
+		t.Errorf("invalid logic. comments=%v", clocFile.Comments)
+	}
+	if clocFile.Mixed != 3 {
+		t.Errorf("invalid logic. mixed=%v", clocFile.Mixed)
+	}
+}
+
+func TestAnalyzeReaderMixedWithoutStringLiterals(t *testing.T) {
+	tests := []struct {
+		lang, line string
+		mixed      int32
+	}{
+		{"Visual Basic", "x = \"a\" ' note", 1},
+		{"Batch", "echo x & rem note", 1},
+		{"Batch", "set remote=1", 0},
+		{"SQL", "SELECT '--' FROM t -- note", 1},
+		{"FORTRAN Legacy", "      x = y * z", 0},
+		{"Lisp", "(quote 'a) ; note", 1},
+	}
+	langs := NewDefinedLanguages()
+	for _, tt := range tests {
+		clocFile := AnalyzeReader("test", langs.Langs[tt.lang], bytes.NewBufferString(tt.line+"\n"), NewClocOptions())
+		if clocFile.Code != 1 || clocFile.Mixed != tt.mixed {
+			t.Errorf("invalid logic. lang=%v line=%q code=%v mixed=%v", tt.lang, tt.line, clocFile.Code, clocFile.Mixed)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package gocloc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// gitRevisionFs is a read-only afero.Fs of the files of a git revision.
+type gitRevisionFs struct {
+	repo    *gitRepository
+	tree    string
+	modTime time.Time
+	// root is the work tree of the repository, relative names are resolved from dir.
+	root string
+	dir  string
+}
+
+// NewGitRevisionFs returns a read-only file system of the files of rev, a commit, tag or branch
+// of the git repository at repo. The files are read from the loose and packed objects of the
+// repository without checking rev out. Relative names are resolved from repo.
+func NewGitRevisionFs(repo, rev string) (afero.Fs, error) {
+	return newGitRevisionFs(repo, rev, "")
+}
+
+func newGitRevisionFs(repo, rev, dir string) (*gitRevisionFs, error) {
+	root, err := filepath.Abs(repo)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = root
+	}
+	r, err := openGitRepository(root)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := r.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	tree, modTime, err := r.treeOf(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &gitRevisionFs{
+		repo:    r,
+		tree:    tree,
+		modTime: modTime,
+		root:    root,
+		dir:     dir,
+	}, nil
+}
+
+// gitRevisionOptions returns a copy of opts reading paths at opts.GitRev from their git repository.
+func gitRevisionOptions(opts *ClocOptions, paths []string) (*ClocOptions, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root := ""
+	for _, path := range paths {
+		abs := path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(cwd, abs)
+		}
+		repo, ok := findGitRepository(afero.NewOsFs(), abs)
+		if !ok {
+			return nil, fmt.Errorf("%s is not in a git repository", path)
+		}
+		if root != "" && repo != root {
+			return nil, fmt.Errorf("%s is not in the git repository %s", path, root)
+		}
+		root = repo
+	}
+	if root == "" {
+		return nil, errors.New("no paths to read from the git repository")
+	}
+
+	fsys, err := newGitRevisionFs(root, opts.GitRev, cwd)
+	if err != nil {
+		return nil, err
+	}
+	gitOpts := *opts
+	gitOpts.Fs = fsys
+	gitOpts.GitRev = ""
+	return &gitOpts, nil
+}
+
+// lookup returns the tree entry of name.
+func (gfs *gitRevisionFs) lookup(op, name string) (gitTreeEntry, error) {
+	notExist := &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(gfs.dir, path)
+	}
+	rel, err := filepath.Rel(gfs.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return gitTreeEntry{}, notExist
+	}
+
+	entry := gitTreeEntry{name: filepath.Base(name), mode: gitModeTree, hash: gfs.tree}
+	if rel == "." {
+		return entry, nil
+	}
+	for _, component := range strings.Split(filepath.ToSlash(rel), "/") {
+		if !entry.isDir() {
+			return gitTreeEntry{}, notExist
+		}
+		entries, err := gfs.repo.readTree(entry.hash)
+		if err != nil {
+			return gitTreeEntry{}, &os.PathError{Op: op, Path: name, Err: err}
+		}
+		found := false
+		for _, e := range entries {
+			if e.name == component && (e.isDir() || e.isFile()) {
+				entry, found = e, true
+				break
+			}
+		}
+		if !found {
+			return gitTreeEntry{}, notExist
+		}
+	}
+	return entry, nil
+}
+
+func (gfs *gitRevisionFs) fileInfo(entry gitTreeEntry) *gitFileInfo {
+	return &gitFileInfo{entry: entry, fs: gfs}
+}
+
+// Open opens the file or the directory name of the revision.
+func (gfs *gitRevisionFs) Open(name string) (afero.File, error) {
+	entry, err := gfs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f := &gitFile{name: name, info: gfs.fileInfo(entry)}
+	if entry.isDir() {
+		entries, err := gfs.repo.readTree(entry.hash)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		for _, e := range entries {
+			if e.isDir() || e.isFile() {
+				f.entries = append(f.entries, gfs.fileInfo(e))
+			}
+		}
+		return f, nil
+	}
+
+	_, content, err := gfs.repo.readObject(entry.hash)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	f.reader = bytes.NewReader(content)
+	return f, nil
+}
+
+// OpenFile opens name for reading, the file system is read-only.
+func (gfs *gitRevisionFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+	return gfs.Open(name)
+}
+
+// Stat returns the os.FileInfo of name.
+func (gfs *gitRevisionFs) Stat(name string) (os.FileInfo, error) {
+	entry, err := gfs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return gfs.fileInfo(entry), nil
+}
+
+// Name returns the name of the file system.
+func (gfs *gitRevisionFs) Name() string {
+	return "GitRevisionFs"
+}
+
+func (gfs *gitRevisionFs) Create(name string) (afero.File, error) {
+	return nil, &os.PathError{Op: "create", Path: name, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) MkdirAll(path string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) RemoveAll(path string) error {
+	return &os.PathError{Op: "remove", Path: path, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: os.ErrPermission}
+}
+
+func (gfs *gitRevisionFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrPermission}
+}
+
+// gitFileInfo is the os.FileInfo of a tree entry, modified at the time of the commit.
+type gitFileInfo struct {
+	entry gitTreeEntry
+	fs    *gitRevisionFs
+}
+
+func (fi *gitFileInfo) Name() string {
+	return fi.entry.name
+}
+
+// Size returns the size of a file, read from the header of its object.
+func (fi *gitFileInfo) Size() int64 {
+	if fi.entry.isDir() {
+		return 0
+	}
+	size, _ := fi.fs.repo.objectSize(fi.entry.hash)
+	return size
+}
+
+func (fi *gitFileInfo) Mode() os.FileMode {
+	if fi.entry.isDir() {
+		return os.ModeDir | 0555
+	}
+	if fi.entry.mode&gitModeExec != 0 {
+		return 0555
+	}
+	return 0444
+}
+
+func (fi *gitFileInfo) ModTime() time.Time {
+	return fi.fs.modTime
+}
+
+func (fi *gitFileInfo) IsDir() bool {
+	return fi.entry.isDir()
+}
+
+func (fi *gitFileInfo) Sys() interface{} {
+	return nil
+}
+
+// gitFile is an open file or directory of a gitRevisionFs.
+type gitFile struct {
+	name string
+	info *gitFileInfo
+	// reader has the content of a file, entries and offset list a directory.
+	reader  *bytes.Reader
+	entries []os.FileInfo
+	offset  int
+}
+
+func (f *gitFile) Name() string {
+	return f.name
+}
+
+func (f *gitFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *gitFile) Close() error {
+	return nil
+}
+
+func (f *gitFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	return f.reader.Read(p)
+}
+
+func (f *gitFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.reader == nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	return f.reader.ReadAt(p, off)
+}
+
+func (f *gitFile) Seek(offset int64, whence int) (int64, error) {
+	if f.reader == nil {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: errors.New("is a directory")}
+	}
+	return f.reader.Seek(offset, whence)
+}
+
+// Readdir returns the next count entries of the directory, or all of them when count <= 0.
+func (f *gitFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	rest := f.entries[f.offset:]
+	if count <= 0 {
+		f.offset = len(f.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	f.offset += count
+	return rest[:count], nil
+}
+
+func (f *gitFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+func (f *gitFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+}
+
+func (f *gitFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+}
+
+func (f *gitFile) WriteString(s string) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+}
+
+func (f *gitFile) Sync() error {
+	return nil
+}
+
+func (f *gitFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrPermission}
+}
@@ -0,0 +1,917 @@
+package gocloc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"container/list"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the git objects, as numbered in the pack files.
+const (
+	gitObjCommit   = 1
+	gitObjTree     = 2
+	gitObjBlob     = 3
+	gitObjTag      = 4
+	gitObjOfsDelta = 6
+	gitObjRefDelta = 7
+)
+
+var gitObjectTypes = map[string]int{
+	"commit": gitObjCommit,
+	"tree":   gitObjTree,
+	"blob":   gitObjBlob,
+	"tag":    gitObjTag,
+}
+
+// Modes of the git tree entries.
+const (
+	gitModeTypeMask = 0170000
+	gitModeTree     = 0040000
+	gitModeFile     = 0100000
+	gitModeExec     = 0000100
+)
+
+const gitHashLen = 20
+
+// maxGitDepth limits the chains of symbolic references, tags and deltas.
+const maxGitDepth = 64
+
+// gitCacheSize is the size of the cache of inflated objects and delta bases, in bytes.
+const gitCacheSize = 32 << 20
+
+// gitRepository reads the objects of a local git repository, loose and packed,
+// without the git command.
+type gitRepository struct {
+	// gitDir is the git directory, commonDir has the objects and refs shared by its worktrees.
+	gitDir    string
+	commonDir string
+
+	packsOnce sync.Once
+	packs     []*gitPack
+	packsErr  error
+
+	mu    sync.Mutex
+	trees map[string][]gitTreeEntry
+
+	// objects keeps the recently inflated objects, as a file is opened several times by the
+	// walk and the analysis, and the objects of a pack share their delta bases.
+	objects *gitObjectCache
+}
+
+// gitObjectCache is a LRU cache of the contents of git objects, by hash or by pack offset.
+type gitObjectCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	lru     *list.List
+	items   map[string]*list.Element
+}
+
+type gitCachedObject struct {
+	key     string
+	typ     int
+	content []byte
+}
+
+func newGitObjectCache(maxSize int) *gitObjectCache {
+	return &gitObjectCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// get returns the type and the content of the object key, the content must not be modified.
+func (c *gitObjectCache) get(key string) (int, []byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return 0, nil, false
+	}
+	c.lru.MoveToFront(e)
+	obj := e.Value.(*gitCachedObject)
+	return obj.typ, obj.content, true
+}
+
+// add adds the object key, evicting the least recently used objects over the size of the cache.
+// The objects over an eighth of the cache are not kept.
+func (c *gitObjectCache) add(key string, typ int, content []byte) {
+	if len(content) > c.maxSize/8 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.lru.PushFront(&gitCachedObject{key: key, typ: typ, content: content})
+	c.size += len(content)
+	for c.size > c.maxSize {
+		e := c.lru.Back()
+		obj := e.Value.(*gitCachedObject)
+		c.lru.Remove(e)
+		delete(c.items, obj.key)
+		c.size -= len(obj.content)
+	}
+}
+
+// gitTreeEntry is a file or a directory of a git tree.
+type gitTreeEntry struct {
+	name string
+	mode uint32
+	hash string
+}
+
+func (e gitTreeEntry) isDir() bool {
+	return e.mode&gitModeTypeMask == gitModeTree
+}
+
+// isFile reports whether e is a regular file, symbolic links and submodules are not counted.
+func (e gitTreeEntry) isFile() bool {
+	return e.mode&gitModeTypeMask == gitModeFile
+}
+
+// openGitRepository opens the git repository of the work tree path, or the git directory path.
+func openGitRepository(path string) (*gitRepository, error) {
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	switch {
+	case err != nil:
+		// a bare repository or a git directory
+		gitDir = path
+	case !info.IsDir():
+		// worktrees and submodules have a .git file pointing to their git directory
+		b, err := os.ReadFile(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		dir, ok := cutPrefix(strings.TrimSpace(string(b)), "gitdir:")
+		if !ok {
+			return nil, fmt.Errorf("invalid .git file: %s", gitDir)
+		}
+		gitDir = resolveGitPath(path, strings.TrimSpace(dir))
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "HEAD")); err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", path)
+	}
+
+	commonDir := gitDir
+	if b, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolveGitPath(gitDir, strings.TrimSpace(string(b)))
+	}
+	if format := gitObjectFormat(commonDir); format != "sha1" {
+		return nil, fmt.Errorf("unsupported git object format %s: %s, only SHA-1 repositories can be read", format, path)
+	}
+	return &gitRepository{
+		gitDir:    gitDir,
+		commonDir: commonDir,
+		trees:     make(map[string][]gitTreeEntry),
+		objects:   newGitObjectCache(gitCacheSize),
+	}, nil
+}
+
+// gitObjectFormat returns the hash algorithm of the objects, extensions.objectFormat of the
+// config of the git directory dir, sha1 by default.
+func gitObjectFormat(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "config"))
+	if err != nil {
+		return "sha1"
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(line, "[] \t"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && section == "extensions" && strings.EqualFold(strings.TrimSpace(key), "objectformat") {
+			return strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))
+		}
+	}
+	return "sha1"
+}
+
+func resolveGitPath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isGitHash(s string) bool {
+	return len(s) == gitHashLen*2 && isHex(s)
+}
+
+// resolve returns the hash of rev: a full or abbreviated hash, HEAD, or the name of a branch,
+// tag or reference, looked up in the order of git rev-parse, followed by any number of the
+// suffixes ~N (the Nth first-parent ancestor), ^N (the Nth parent) and ^{commit}, ^{tree}
+// or ^{} (the object a tag points to). ~ and ^ without N stand for ~1 and ^1.
+func (r *gitRepository) resolve(rev string) (string, error) {
+	name, suffixes := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		name, suffixes = rev[:i], rev[i:]
+	}
+	hash, err := r.resolveName(name)
+	if err != nil {
+		return "", err
+	}
+
+	for suffixes != "" {
+		op := suffixes[0]
+		suffixes = suffixes[1:]
+		if op == '^' && strings.HasPrefix(suffixes, "{") {
+			end := strings.IndexByte(suffixes, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unknown git revision: %s", rev)
+			}
+			if hash, err = r.peel(hash, suffixes[1:end]); err != nil {
+				return "", err
+			}
+			suffixes = suffixes[end+1:]
+			continue
+		}
+
+		digits := len(suffixes) - len(strings.TrimLeft(suffixes, "0123456789"))
+		n := 1
+		if digits > 0 {
+			if n, err = strconv.Atoi(suffixes[:digits]); err != nil {
+				return "", fmt.Errorf("unknown git revision: %s", rev)
+			}
+			suffixes = suffixes[digits:]
+		}
+		if op == '^' {
+			hash, err = r.parent(hash, n)
+		} else {
+			for i := 0; i < n && err == nil; i++ {
+				hash, err = r.parent(hash, 1)
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", rev, err)
+		}
+	}
+	return hash, nil
+}
+
+// peel returns the object of type typ that the tags from hash point to, or the first object
+// that is not a tag when typ is "".
+func (r *gitRepository) peel(hash, typ string) (string, error) {
+	for depth := 0; depth < maxGitDepth; depth++ {
+		t, content, err := r.readObject(hash)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case typ == "" && t != gitObjTag, gitObjectTypes[typ] == t:
+			return hash, nil
+		case t == gitObjTag:
+			hash = gitHeader(content, "object")
+			if !isGitHash(hash) {
+				return "", fmt.Errorf("corrupt git tag: %s", hash)
+			}
+		case t == gitObjCommit && typ == "tree":
+			tree := gitHeader(content, "tree")
+			if !isGitHash(tree) {
+				return "", fmt.Errorf("corrupt git commit: %s", hash)
+			}
+			return tree, nil
+		default:
+			return "", fmt.Errorf("git object %s is not a %s", hash, typ)
+		}
+	}
+	return "", fmt.Errorf("too many nested git tags: %s", hash)
+}
+
+// parent returns the nth parent of the commit hash, the commit itself for 0.
+func (r *gitRepository) parent(hash string, n int) (string, error) {
+	commit, err := r.peel(hash, "commit")
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return commit, nil
+	}
+	_, content, err := r.readObject(commit)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			break
+		}
+		if parent, ok := cutPrefix(line, "parent "); ok {
+			if n--; n == 0 {
+				return parent, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("git commit %s has no such parent", commit)
+}
+
+// resolveName returns the hash of name, a full or abbreviated hash or a reference.
+func (r *gitRepository) resolveName(rev string) (string, error) {
+	if isGitHash(rev) {
+		return strings.ToLower(rev), nil
+	}
+	candidates := []string{
+		rev,
+		"refs/" + rev,
+		"refs/tags/" + rev,
+		"refs/heads/" + rev,
+		"refs/remotes/" + rev,
+		"refs/remotes/" + rev + "/HEAD",
+	}
+	for _, name := range candidates {
+		if hash, ok := r.readRef(name, 0); ok {
+			return hash, nil
+		}
+	}
+	if len(rev) >= 4 && isHex(rev) {
+		return r.expandHash(strings.ToLower(rev))
+	}
+	return "", fmt.Errorf("unknown git revision: %s", rev)
+}
+
+// readRef returns the hash of the reference name, following symbolic references.
+func (r *gitRepository) readRef(name string, depth int) (string, bool) {
+	if depth > maxGitDepth || name == "" || strings.Contains(name, "..") {
+		return "", false
+	}
+	// HEAD and the other pseudo references belong to the worktree
+	dir := r.gitDir
+	if strings.HasPrefix(name, "refs/") {
+		dir = r.commonDir
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+		s := strings.TrimSpace(string(b))
+		if target, ok := cutPrefix(s, "ref:"); ok {
+			return r.readRef(strings.TrimSpace(target), depth+1)
+		}
+		if isGitHash(s) {
+			return strings.ToLower(s), true
+		}
+		return "", false
+	}
+	return r.packedRef(name)
+}
+
+// packedRef returns the hash of the reference name in the packed-refs file.
+func (r *gitRepository) packedRef(name string) (string, bool) {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			// the header and the peeled tags
+			continue
+		}
+		hash, ref, ok := strings.Cut(line, " ")
+		if ok && ref == name && isGitHash(hash) {
+			return strings.ToLower(hash), true
+		}
+	}
+	return "", false
+}
+
+// expandHash returns the only object whose hash starts with prefix.
+func (r *gitRepository) expandHash(prefix string) (string, error) {
+	matches := make(map[string]struct{})
+	dir := filepath.Join(r.commonDir, "objects", prefix[:2])
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if hash := prefix[:2] + e.Name(); isGitHash(hash) && strings.HasPrefix(hash, prefix) {
+				matches[hash] = struct{}{}
+			}
+		}
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range packs {
+		for _, hash := range p.prefixMatches(prefix) {
+			matches[hash] = struct{}{}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown git revision: %s", prefix)
+	case 1:
+		for hash := range matches {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("ambiguous git revision: %s", prefix)
+}
+
+// readObject returns the type and the content of the object hash, the content must not be modified.
+func (r *gitRepository) readObject(hash string) (int, []byte, error) {
+	if typ, content, ok := r.objects.get(hash); ok {
+		return typ, content, nil
+	}
+	typ, _, content, err := r.object(hash, true)
+	if err == nil {
+		r.objects.add(hash, typ, content)
+	}
+	return typ, content, err
+}
+
+// objectSize returns the size of the content of the object hash without reading the whole object.
+func (r *gitRepository) objectSize(hash string) (int64, error) {
+	_, size, _, err := r.object(hash, false)
+	return size, err
+}
+
+func (r *gitRepository) object(hash string, withContent bool) (int, int64, []byte, error) {
+	typ, size, content, err := r.readLooseObject(hash, withContent)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return typ, size, content, err
+	}
+
+	id, err := hex.DecodeString(hash)
+	if err != nil || len(id) != gitHashLen {
+		return 0, 0, nil, fmt.Errorf("invalid git object name: %s", hash)
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	for _, p := range packs {
+		if offset, ok := p.find(id); ok {
+			return r.readPackedObject(p, offset, withContent)
+		}
+	}
+	return 0, 0, nil, fmt.Errorf("git object not found: %s", hash)
+}
+
+func (r *gitRepository) readLooseObject(hash string, withContent bool) (int, int64, []byte, error) {
+	f, err := os.Open(filepath.Join(r.commonDir, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("corrupt git object %s: %w", hash, err)
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+	header, err := br.ReadString(0)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("corrupt git object %s: %w", hash, err)
+	}
+	name, sizeStr, _ := strings.Cut(strings.TrimSuffix(header, "\x00"), " ")
+	typ, ok := gitObjectTypes[name]
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if !ok || err != nil || size < 0 {
+		return 0, 0, nil, fmt.Errorf("corrupt git object %s: invalid header", hash)
+	}
+	if !withContent {
+		return typ, size, nil, nil
+	}
+
+	content := make([]byte, size)
+	if _, err := io.ReadFull(br, content); err != nil {
+		return 0, 0, nil, fmt.Errorf("corrupt git object %s: %w", hash, err)
+	}
+	return typ, size, content, nil
+}
+
+// gitPack is a pack file with its version 2 index.
+type gitPack struct {
+	path    string
+	fanout  [256]uint32
+	hashes  []byte
+	offsets []byte
+	large   []byte
+}
+
+// loadPacks reads the indexes of the pack files once.
+func (r *gitRepository) loadPacks() ([]*gitPack, error) {
+	r.packsOnce.Do(func() {
+		idxs, _ := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+		for _, idx := range idxs {
+			p, err := loadGitPack(idx)
+			if err != nil {
+				r.packsErr = err
+				return
+			}
+			r.packs = append(r.packs, p)
+		}
+	})
+	return r.packs, r.packsErr
+}
+
+func loadGitPack(idxPath string) (*gitPack, error) {
+	b, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	const headerLen = 8 + 256*4
+	if len(b) < headerLen {
+		return nil, fmt.Errorf("corrupt git pack index: %s", idxPath)
+	}
+	if !bytes.Equal(b[:4], []byte("\xfftOc")) {
+		// the version 1 indexes of git before 1.5.2 have no header
+		return nil, fmt.Errorf("unsupported git pack index version 1: %s, rewrite it with git index-pack --index-version=2", idxPath)
+	}
+	if version := binary.BigEndian.Uint32(b[4:]); version != 2 {
+		return nil, fmt.Errorf("unsupported git pack index version %d: %s", version, idxPath)
+	}
+
+	p := &gitPack{path: strings.TrimSuffix(idxPath, ".idx") + ".pack"}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(b[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	pos := headerLen
+	if len(b) < pos+n*(gitHashLen+4+4) {
+		return nil, fmt.Errorf("corrupt git pack index: %s", idxPath)
+	}
+	p.hashes = b[pos : pos+n*gitHashLen]
+	pos += n * gitHashLen
+	// skip the CRC32 of the objects
+	pos += n * 4
+	p.offsets = b[pos : pos+n*4]
+	pos += n * 4
+	p.large = b[pos:]
+	return p, nil
+}
+
+func (p *gitPack) hash(i int) []byte {
+	return p.hashes[i*gitHashLen : (i+1)*gitHashLen]
+}
+
+// bucket returns the range of the objects whose hash starts with the byte first.
+func (p *gitPack) bucket(first byte) (lo, hi int) {
+	if first > 0 {
+		lo = int(p.fanout[first-1])
+	}
+	return lo, int(p.fanout[first])
+}
+
+// find returns the offset of the object id in the pack file.
+func (p *gitPack) find(id []byte) (int64, bool) {
+	lo, hi := p.bucket(id[0])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.hash(lo+i), id) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.hash(i), id) {
+		return 0, false
+	}
+
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	// offsets over 2GB are in the table of 8 byte offsets
+	j := int(offset&0x7fffffff) * 8
+	if j+8 > len(p.large) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[j:])), true
+}
+
+// prefixMatches returns the hashes of the objects starting with the hex prefix.
+func (p *gitPack) prefixMatches(prefix string) []string {
+	first, err := hex.DecodeString(prefix[:2])
+	if err != nil {
+		return nil
+	}
+	var matches []string
+	lo, hi := p.bucket(first[0])
+	for i := lo; i < hi; i++ {
+		if hash := hex.EncodeToString(p.hash(i)); strings.HasPrefix(hash, prefix) {
+			matches = append(matches, hash)
+		}
+	}
+	return matches
+}
+
+func (r *gitRepository) readPackedObject(p *gitPack, offset int64, withContent bool) (int, int64, []byte, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+	return r.readPackEntry(f, p, offset, withContent, 0)
+}
+
+// readPackEntry reads the object at offset of the pack file f, applying the deltas.
+func (r *gitRepository) readPackEntry(f *os.File, p *gitPack, offset int64, withContent bool, depth int) (int, int64, []byte, error) {
+	corrupt := func(err error) (int, int64, []byte, error) {
+		return 0, 0, nil, fmt.Errorf("corrupt git pack %s at %d: %v", p.path, offset, err)
+	}
+	if depth > maxGitDepth {
+		return corrupt(errors.New("delta chain too long"))
+	}
+	// the delta bases are shared by the objects of the pack
+	baseKey := fmt.Sprintf("%s@%d", p.path, offset)
+	if depth > 0 && withContent {
+		if typ, content, ok := r.objects.get(baseKey); ok {
+			return typ, int64(len(content)), content, nil
+		}
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	c, err := br.ReadByte()
+	if err != nil {
+		return corrupt(err)
+	}
+	typ := int(c>>4) & 7
+	size := int64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = br.ReadByte(); err != nil {
+			return corrupt(err)
+		}
+		size |= int64(c&0x7f) << shift
+	}
+
+	var baseOffset int64
+	var baseHash string
+	switch typ {
+	case gitObjCommit, gitObjTree, gitObjBlob, gitObjTag:
+		if !withContent {
+			return typ, size, nil, nil
+		}
+	case gitObjOfsDelta:
+		if c, err = br.ReadByte(); err != nil {
+			return corrupt(err)
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return corrupt(err)
+			}
+			rel = (rel+1)<<7 | int64(c&0x7f)
+		}
+		baseOffset = offset - rel
+	case gitObjRefDelta:
+		id := make([]byte, gitHashLen)
+		if _, err := io.ReadFull(br, id); err != nil {
+			return corrupt(err)
+		}
+		baseHash = hex.EncodeToString(id)
+	default:
+		return corrupt(fmt.Errorf("unknown object type %d", typ))
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return corrupt(err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return corrupt(err)
+	}
+	if typ != gitObjOfsDelta && typ != gitObjRefDelta {
+		if int64(len(data)) != size {
+			return corrupt(errors.New("size mismatch"))
+		}
+		if depth > 0 {
+			r.objects.add(baseKey, typ, data)
+		}
+		return typ, size, data, nil
+	}
+
+	var baseTyp int
+	var base []byte
+	if baseHash != "" {
+		baseTyp, _, base, err = r.object(baseHash, withContent)
+	} else {
+		baseTyp, _, base, err = r.readPackEntry(f, p, baseOffset, withContent, depth+1)
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if !withContent {
+		// the header of the delta has the size of the result
+		if _, rest, ok := readGitDeltaSize(data); ok {
+			if dstSize, _, ok := readGitDeltaSize(rest); ok {
+				return baseTyp, int64(dstSize), nil, nil
+			}
+		}
+		return corrupt(errors.New("invalid delta"))
+	}
+	content, err := applyGitDelta(base, data)
+	if err != nil {
+		return corrupt(err)
+	}
+	if depth > 0 {
+		r.objects.add(baseKey, baseTyp, content)
+	}
+	return baseTyp, int64(len(content)), content, nil
+}
+
+// readGitDeltaSize reads a size of the header of a delta.
+func readGitDeltaSize(b []byte) (int, []byte, bool) {
+	size, shift := 0, 0
+	for i, c := range b {
+		size |= int(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			return size, b[i+1:], true
+		}
+	}
+	return 0, nil, false
+}
+
+// applyGitDelta returns base with the copy and insert instructions of delta applied.
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	errInvalid := errors.New("invalid delta")
+	srcSize, delta, ok := readGitDeltaSize(delta)
+	if !ok || srcSize != len(base) {
+		return nil, errInvalid
+	}
+	dstSize, delta, ok := readGitDeltaSize(delta)
+	if !ok {
+		return nil, errInvalid
+	}
+
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// copy from base, the bits of op tell which bytes of offset and size follow
+			var offset, size int
+			for i := uint(0); i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, errInvalid
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					size |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errInvalid
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0:
+			// insert the next op bytes
+			n := int(op)
+			if n > len(delta) {
+				return nil, errInvalid
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+		default:
+			return nil, errInvalid
+		}
+	}
+	if len(out) != dstSize {
+		return nil, errInvalid
+	}
+	return out, nil
+}
+
+// gitHeader returns the value of the header key of a commit or a tag.
+func gitHeader(content []byte, key string) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			// the message follows the headers
+			break
+		}
+		if value, ok := cutPrefix(line, key+" "); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// treeOf returns the tree of the commit, tag or tree hash, and the time of the commit.
+func (r *gitRepository) treeOf(hash string) (string, time.Time, error) {
+	for depth := 0; depth < maxGitDepth; depth++ {
+		typ, content, err := r.readObject(hash)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		switch typ {
+		case gitObjTree:
+			return hash, time.Time{}, nil
+		case gitObjCommit:
+			tree := gitHeader(content, "tree")
+			if !isGitHash(tree) {
+				return "", time.Time{}, fmt.Errorf("corrupt git commit: %s", hash)
+			}
+			return tree, gitCommitTime(gitHeader(content, "committer")), nil
+		case gitObjTag:
+			// an annotated tag points to another object
+			hash = gitHeader(content, "object")
+			if !isGitHash(hash) {
+				return "", time.Time{}, fmt.Errorf("corrupt git tag: %s", hash)
+			}
+		default:
+			return "", time.Time{}, fmt.Errorf("not a git commit: %s", hash)
+		}
+	}
+	return "", time.Time{}, fmt.Errorf("too many nested git tags: %s", hash)
+}
+
+// gitCommitTime returns the time of the committer header "name <email> 1700000000 +0900".
+func gitCommitTime(committer string) time.Time {
+	fields := strings.Fields(committer[strings.LastIndexByte(committer, '>')+1:])
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// readTree returns the entries of the tree hash.
+func (r *gitRepository) readTree(hash string) ([]gitTreeEntry, error) {
+	r.mu.Lock()
+	entries, ok := r.trees[hash]
+	r.mu.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	typ, content, err := r.readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != gitObjTree {
+		return nil, fmt.Errorf("not a git tree: %s", hash)
+	}
+	// each entry is "<octal mode> <name>\x00<binary hash>"
+	for len(content) > 0 {
+		sp := bytes.IndexByte(content, ' ')
+		nul := bytes.IndexByte(content, 0)
+		if sp < 0 || nul < sp || len(content) < nul+1+gitHashLen {
+			return nil, fmt.Errorf("corrupt git tree: %s", hash)
+		}
+		mode, err := strconv.ParseUint(string(content[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt git tree: %s", hash)
+		}
+		entries = append(entries, gitTreeEntry{
+			name: string(content[sp+1 : nul]),
+			mode: uint32(mode),
+			hash: hex.EncodeToString(content[nul+1 : nul+1+gitHashLen]),
+		})
+		content = content[nul+1+gitHashLen:]
+	}
+
+	r.mu.Lock()
+	r.trees[hash] = entries
+	r.mu.Unlock()
+	return entries, nil
+}
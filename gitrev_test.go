@@ -0,0 +1,354 @@
+package gocloc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeGitObject writes a loose object to the git directory gitDir and returns its hash.
+func writeGitObject(t *testing.T, gitDir, typ string, content []byte) string {
+	data := append([]byte(fmt.Sprintf("%s %d\x00", typ, len(content))), content...)
+	sum := sha1.Sum(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	path := filepath.Join(gitDir, "objects", hash[:2], hash[2:])
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o444); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// writeGitTree writes a tree of entries, "<mode> <name>" to the hash, and returns its hash.
+func writeGitTree(t *testing.T, gitDir string, entries map[string]string) string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.SplitN(names[i], " ", 2)[1] < strings.SplitN(names[j], " ", 2)[1]
+	})
+	var buf bytes.Buffer
+	for _, name := range names {
+		id, _ := hex.DecodeString(entries[name])
+		buf.WriteString(name + "\x00")
+		buf.Write(id)
+	}
+	return writeGitObject(t, gitDir, "tree", buf.Bytes())
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	args = append([]string{
+		"-c", "user.name=gocloc", "-c", "user.email=gocloc@example.com",
+		"-c", "init.defaultBranch=main", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false",
+	}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func analyzeFileNames(result *Result) []string {
+	var names []string
+	for name := range result.Files {
+		names = append(names, filepath.ToSlash(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGitRevisionFsLooseObjects(t *testing.T) {
+	dir := t.TempDir()
+	gitDir := filepath.Join(dir, ".git")
+	writeTestFiles(t, dir, map[string]string{
+		".git/HEAD": "ref: refs/heads/main\n",
+		// the work tree differs from the commit
+		"main.go": "package main\n",
+	})
+
+	mainGo := writeGitObject(t, gitDir, "blob", []byte("package main\n\n// main is the entry point.\nfunc main() {\n}\n"))
+	utilPy := writeGitObject(t, gitDir, "blob", []byte("# util\ndef f():\n    pass\n"))
+	link := writeGitObject(t, gitDir, "blob", []byte("main.go"))
+	src := writeGitTree(t, gitDir, map[string]string{"100755 util.py": utilPy})
+	tree := writeGitTree(t, gitDir, map[string]string{
+		"100644 main.go": mainGo,
+		"40000 src":      src,
+		"120000 link.go": link,
+	})
+	commit := writeGitObject(t, gitDir, "commit", []byte(fmt.Sprintf(
+		"tree %s\nauthor gocloc <gocloc@example.com> 1700000000 +0900\ncommitter gocloc <gocloc@example.com> 1700000000 +0900\n\ninitial\n", tree)))
+	tag := writeGitObject(t, gitDir, "tag", []byte(fmt.Sprintf(
+		"object %s\ntype commit\ntag v1\ntagger gocloc <gocloc@example.com> 1700000000 +0900\n\nrelease\n", commit)))
+	writeTestFiles(t, dir, map[string]string{
+		".git/refs/heads/main": commit + "\n",
+		".git/packed-refs":     fmt.Sprintf("# pack-refs with: peeled fully-peeled sorted\n%s refs/tags/v1\n^%s\n", tag, commit),
+	})
+
+	for _, rev := range []string{"HEAD", "main", "refs/heads/main", "v1", "tags/v1", commit, commit[:7]} {
+		fsys, err := NewGitRevisionFs(dir, rev)
+		if err != nil {
+			t.Fatalf("invalid logic. rev=%v err=[%v]", rev, err)
+		}
+		opts := NewClocOptions()
+		opts.Fs = fsys
+		result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{"."})
+		if err != nil {
+			t.Fatalf("invalid logic. rev=%v err=[%v]", rev, err)
+		}
+		if names := analyzeFileNames(result); strings.Join(names, ",") != "main.go,src/util.py" {
+			t.Errorf("invalid logic. rev=%v files=%v", rev, names)
+		}
+		if result.Total.Code != 5 || result.Total.Comments != 2 || result.Total.Blanks != 1 {
+			t.Errorf("invalid logic. rev=%v total=%+v", rev, result.Total)
+		}
+	}
+
+	fsys, _ := NewGitRevisionFs(dir, "v1")
+	info, err := fsys.Stat("src/util.py")
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if info.Size() != 25 || info.ModTime().Unix() != 1700000000 || info.Mode()&0111 == 0 {
+		t.Errorf("invalid logic. size=%v modTime=%v mode=%v", info.Size(), info.ModTime(), info.Mode())
+	}
+	if _, err := fsys.Stat("link.go"); !os.IsNotExist(err) {
+		t.Errorf("invalid logic. symbolic link err=[%v]", err)
+	}
+	if _, err := fsys.Create("new.go"); err == nil {
+		t.Errorf("invalid logic. created a file")
+	}
+
+	if _, err := NewGitRevisionFs(dir, "v2"); err == nil {
+		t.Errorf("invalid logic. resolved an unknown revision")
+	}
+}
+
+func TestGitRevisionPackedObjects(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("var v%d = %d // value %d", i, i, i))
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":    "package main\n\n" + strings.Join(lines, "\n") + "\n",
+		"lib/lib.py": "# lib\nimport os\n",
+	})
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "v1")
+	runGit(t, dir, "tag", "-a", "v1", "-m", "v1")
+
+	// the second version is similar, so that the objects of v1 are stored as deltas
+	lines = append(lines, "// the end")
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":    "package main\n\n" + strings.Join(lines, "\n") + "\n",
+		"lib/new.py": "x = 1\n",
+	})
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "v2")
+	runGit(t, dir, "gc", "-q", "--aggressive")
+	os.RemoveAll(filepath.Join(dir, "lib"))
+
+	for rev, want := range map[string]string{"v1": "lib/lib.py,main.go", "main": "lib/lib.py,lib/new.py,main.go"} {
+		opts := NewClocOptions()
+		opts.GitRev = rev
+		result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+		if err != nil {
+			t.Fatalf("invalid logic. rev=%v err=[%v]", rev, err)
+		}
+		var names []string
+		for _, name := range analyzeFileNames(result) {
+			rel, _ := filepath.Rel(dir, name)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		if strings.Join(names, ",") != want {
+			t.Errorf("invalid logic. rev=%v files=%v", rev, names)
+		}
+		mainGo := result.Files[filepath.Join(dir, "main.go")]
+		if rev == "v1" && (mainGo.Code != 51 || mainGo.Comments != 0) {
+			t.Errorf("invalid logic. rev=%v code=%v comments=%v", rev, mainGo.Code, mainGo.Comments)
+		}
+		if rev == "main" && (mainGo.Code != 51 || mainGo.Comments != 1) {
+			t.Errorf("invalid logic. rev=%v code=%v comments=%v", rev, mainGo.Code, mainGo.Comments)
+		}
+	}
+
+	short := runGit(t, dir, "rev-parse", "--short", "HEAD~1")
+	fsys, err := NewGitRevisionFs(dir, short)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if _, err := fsys.Stat("lib/new.py"); !os.IsNotExist(err) {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+}
+
+func TestApplyGitDelta(t *testing.T) {
+	base := []byte("hello, world")
+	// source size 12, result size 11, copy 7 bytes from offset 0, insert 4 bytes
+	delta := []byte{12, 11, 0x80 | 0x01 | 0x10, 0, 7, 4, 'g', 'o', 'c', 'l'}
+	out, err := applyGitDelta(base, delta)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if string(out) != "hello, gocl" {
+		t.Errorf("invalid logic. out=%q", out)
+	}
+
+	if _, err := applyGitDelta(base, []byte{11, 11}); err == nil {
+		t.Errorf("invalid logic. applied a delta of another base")
+	}
+}
+
+func TestGitObjectCache(t *testing.T) {
+	c := newGitObjectCache(80)
+	c.add("a", gitObjBlob, []byte("0123456789"))
+	c.add("b", gitObjBlob, []byte("0123456789"))
+	// too large to be kept
+	c.add("large", gitObjBlob, []byte("0123456789x"))
+	if _, _, ok := c.get("large"); ok {
+		t.Errorf("invalid logic. an object over an eighth of the cache is kept")
+	}
+
+	// a is used last, so that b is evicted first
+	if typ, content, ok := c.get("a"); !ok || typ != gitObjBlob || string(content) != "0123456789" {
+		t.Errorf("invalid logic. typ=%v content=%q ok=%v", typ, content, ok)
+	}
+	for _, key := range []string{"c", "d", "e", "f", "g", "h", "i", "j"} {
+		c.add(key, gitObjTree, []byte("0123456789"))
+	}
+	if _, _, ok := c.get("b"); ok {
+		t.Errorf("invalid logic. the least recently used object is kept")
+	}
+	if _, _, ok := c.get("a"); ok {
+		t.Errorf("invalid logic. the cache is over its size")
+	}
+	if _, _, ok := c.get("j"); !ok || c.size != 80 {
+		t.Errorf("invalid logic. size=%v", c.size)
+	}
+}
+
+func TestGitRevisionUnsupportedFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		".git/HEAD":   "ref: refs/heads/main\n",
+		".git/config": "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tobjectFormat = sha256\n",
+	})
+	if _, err := openGitRepository(dir); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+
+	// a version 1 index begins with the fan-out table
+	idx := filepath.Join(dir, "pack-v1.idx")
+	if err := os.WriteFile(idx, make([]byte, 256*4+24), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadGitPack(idx); err == nil || !strings.Contains(err.Error(), "version 1") {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+}
+
+func TestGitRevisionAncestry(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	commit := func(name string) {
+		writeTestFiles(t, dir, map[string]string{name: "package " + strings.TrimSuffix(name, ".go") + "\n"})
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "-q", "-m", name)
+	}
+	commit("a.go")
+	commit("b.go")
+	runGit(t, dir, "tag", "-a", "v2", "-m", "v2")
+	runGit(t, dir, "checkout", "-q", "-b", "topic", "HEAD~1")
+	commit("c.go")
+	runGit(t, dir, "checkout", "-q", "-")
+	runGit(t, dir, "merge", "-q", "--no-ff", "-m", "merge", "topic")
+	commit("d.go")
+
+	r, err := openGitRepository(dir)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	for _, rev := range []string{"HEAD", "HEAD~", "HEAD^", "HEAD~2", "HEAD^^", "HEAD~1^2", "HEAD~3",
+		"HEAD^{commit}", "HEAD~1^{tree}", "v2^{}", "v2^0", "v2~1"} {
+		want := runGit(t, dir, "rev-parse", rev)
+		got, err := r.resolve(rev)
+		if err != nil {
+			t.Errorf("invalid logic. rev=%v err=[%v]", rev, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("invalid logic. rev=%v hash=%v expected=%v", rev, got, want)
+		}
+	}
+
+	for _, rev := range []string{"HEAD~9", "HEAD^3", "HEAD^{tree}^{commit}", "HEAD^{", "v2^{blob}"} {
+		if hash, err := r.resolve(rev); err == nil {
+			t.Errorf("invalid logic. rev=%v hash=%v", rev, hash)
+		}
+	}
+}
+
+func TestGitRevisionIgnoreFilesAboveRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeTestFiles(t, dir, map[string]string{
+		".gitignore":        "*.gen.go\n",
+		"src/main.go":       "package main\n",
+		"src/main.gen.go":   "package main\n\nvar x = 1\n",
+		"src/secret.py":     "x = 1\n",
+		".git/info/exclude": "secret.py\n",
+	})
+	runGit(t, dir, "add", "-A", "-f")
+	runGit(t, dir, "commit", "-q", "-m", "v1")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	opts := NewClocOptions()
+	opts.GitRev = "HEAD"
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{"src"})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if names := analyzeFileNames(result); strings.Join(names, ",") != filepath.Join("src", "main.go") {
+		t.Errorf("invalid logic. files=%v", names)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/go-enry/go-enry/v2 v2.8.0
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/spf13/afero v1.2.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,5 +1,10 @@
 package gocloc
 
+import (
+	"context"
+	"sync"
+)
+
 // Processor is gocloc analyzing processor.
 type Processor struct {
 	langs *DefinedLanguages
@@ -12,6 +17,10 @@ type Result struct {
 	Files         map[string]*ClocFile
 	Languages     map[string]*Language
 	MaxPathLength int
+	// DuplicateOf maps the files skipped as duplicates to the counted file with the same content.
+	DuplicateOf map[string]string
+	// Duplicates groups the files of DuplicateOf by the counted file, with their lines by language.
+	Duplicates *Duplicates
 }
 
 // NewProcessor returns Processor.
@@ -23,34 +32,165 @@ func NewProcessor(langs *DefinedLanguages, options *ClocOptions) *Processor {
 }
 
 // Analyze executes gocloc parsing for the directory of the paths argument and returns the result.
+// Files are analyzed while the directories are still being walked.
 func (p *Processor) Analyze(paths []string) (*Result, error) {
-	total := NewLanguage("TOTAL", []string{}, [][]string{{"", ""}})
-	languages, err := getAllFiles(paths, p.langs, p.opts)
-	if err != nil {
-		return nil, err
+	return p.analyze(context.Background(), paths, nil)
+}
+
+// AnalyzeContext is like Analyze, but stops walking and counting as soon as ctx is done.
+// In that case it returns the Result of the files counted completely so far together with ctx.Err().
+func (p *Processor) AnalyzeContext(ctx context.Context, paths []string) (*Result, error) {
+	return p.analyze(ctx, paths, nil)
+}
+
+// AnalyzeStream is like Analyze, but also sends each ClocFile on files as soon as it is counted.
+// The order of the files is not defined when ClocOptions.Jobs is greater than 1.
+// files is closed before AnalyzeStream returns.
+func (p *Processor) AnalyzeStream(paths []string, files chan<- *ClocFile) (*Result, error) {
+	defer close(files)
+	return p.analyze(context.Background(), paths, files)
+}
+
+type analyzeTask struct {
+	file     string
+	language *Language
+}
+
+func (p *Processor) analyze(ctx context.Context, paths []string, stream chan<- *ClocFile) (*Result, error) {
+	opts := p.opts
+	if opts.GitRev != "" {
+		var err error
+		if opts, err = gitRevisionOptions(opts, paths); err != nil {
+			return nil, err
+		}
 	}
-	maxPathLen := 0
-	num := 0
-	for _, lang := range languages {
-		num += len(lang.Files)
-		for _, file := range lang.Files {
-			l := len(file)
-			if maxPathLen < l {
-				maxPathLen = l
+
+	if opts.Embedded && opts.EmbeddedLanguages == nil {
+		embeddedOpts := *opts
+		embeddedOpts.EmbeddedLanguages = p.langs
+		opts = &embeddedOpts
+	}
+
+	jobs := opts.Jobs
+	if opts.Debug || jobs < 1 {
+		// keep the debug log of each file in one piece
+		jobs = 1
+	}
+
+	var languages map[string]*Language
+	var walkErr error
+	duplicateOf := make(map[string]string)
+	onDuplicate := func(path, original string) {
+		duplicateOf[path] = original
+	}
+	tasks := make(chan analyzeTask, jobs)
+	if opts.Debug {
+		// walk first, so the debug logs of discovery and counting do not interleave
+		var pending []analyzeTask
+		languages, walkErr = walkFiles(ctx, paths, p.langs, opts, func(path string, language *Language) {
+			pending = append(pending, analyzeTask{file: path, language: language})
+		}, onDuplicate)
+		go func() {
+			defer close(tasks)
+			for _, t := range pending {
+				tasks <- t
 			}
+		}()
+	} else {
+		go func() {
+			defer close(tasks)
+			languages, walkErr = walkFiles(ctx, paths, p.langs, opts, func(path string, language *Language) {
+				select {
+				case tasks <- analyzeTask{file: path, language: language}:
+				case <-ctx.Done():
+				}
+			}, onDuplicate)
+		}()
+	}
+
+	var cache *fileCache
+	if opts.CacheDir != "" && opts.OnCode == nil && opts.OnComment == nil && opts.OnBlank == nil {
+		cache = openFileCache(opts.CacheDir, opts.ResetCache)
+	}
+
+	results := make(chan *ClocFile, jobs)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				var cf *ClocFile
+				var err error
+				if cache != nil {
+					cf, err = cache.analyzeFile(ctx, t.file, t.language, opts)
+				} else {
+					cf, err = analyzeFile(ctx, t.file, t.language, opts)
+				}
+				if err != nil {
+					// cancelled while counting, the file is left out of the Result
+					continue
+				}
+				cf.Lang = t.language.Name
+				results <- cf
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	clocFiles := make(map[string]*ClocFile)
+	for cf := range results {
+		clocFiles[cf.Name] = cf
+		if stream != nil {
+			stream <- cf
+		}
+	}
+	ctxErr := ctx.Err()
+	if walkErr != nil && walkErr != ctxErr {
+		return nil, walkErr
+	}
+	if cache != nil {
+		// a cancelled run has not seen every file
+		var roots []string
+		if ctxErr == nil {
+			for _, path := range paths {
+				roots = append(roots, cacheKey(opts.fs(), path))
+			}
+		}
+		if err := cache.save(roots); err != nil && opts.OnCacheError != nil {
+			opts.OnCacheError(err)
 		}
 	}
-	clocFiles := make(map[string]*ClocFile, num)
 
+	total := NewLanguage("TOTAL", []string{}, [][]string{{"", ""}})
+	maxPathLen := 0
 	for _, language := range languages {
+		if ctxErr != nil {
+			// drop the files found by the walk but not counted before the cancellation
+			counted := language.Files[:0]
+			for _, file := range language.Files {
+				if _, ok := clocFiles[file]; ok {
+					counted = append(counted, file)
+				}
+			}
+			language.Files = counted
+		}
+
 		for _, file := range language.Files {
-			cf := AnalyzeFile(file, language, p.opts)
-			cf.Lang = language.Name
+			if l := len(file); maxPathLen < l {
+				maxPathLen = l
+			}
 
+			cf := clocFiles[file]
 			language.Code += cf.Code
 			language.Comments += cf.Comments
+			language.DocComments += cf.DocComments
 			language.Blanks += cf.Blanks
-			clocFiles[file] = cf
+			language.Mixed += cf.Mixed
+			addEmbeddedFile(language, cf)
 		}
 
 		files := int32(len(language.Files))
@@ -61,7 +201,9 @@ func (p *Processor) Analyze(paths []string) (*Result, error) {
 		total.Total += files
 		total.Blanks += language.Blanks
 		total.Comments += language.Comments
+		total.DocComments += language.DocComments
 		total.Code += language.Code
+		total.Mixed += language.Mixed
 	}
 
 	return &Result{
@@ -69,5 +211,7 @@ func (p *Processor) Analyze(paths []string) (*Result, error) {
 		Files:         clocFiles,
 		Languages:     languages,
 		MaxPathLength: maxPathLen,
-	}, nil
+		DuplicateOf:   duplicateOf,
+		Duplicates:    newDuplicates(duplicateOf, clocFiles),
+	}, ctxErr
 }
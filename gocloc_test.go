@@ -0,0 +1,199 @@
+package gocloc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func writeTestTree(t *testing.T) string {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		goSrc := fmt.Sprintf("package p%d\n\n// comment\nfunc F%d() {}\n%s", i, i, "/*\nblock\n*/\nvar x = 1\n")
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.go", i)), []byte(goSrc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		pySrc := fmt.Sprintf("# comment %d\n\nx = %d\n", i, i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.py", i)), []byte(pySrc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAnalyzeParallelMatchesSequential(t *testing.T) {
+	dir := writeTestTree(t)
+
+	seqOpts := NewClocOptions()
+	seq, err := NewProcessor(NewDefinedLanguages(), seqOpts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	parOpts := NewClocOptions()
+	parOpts.Jobs = 4
+	par, err := NewProcessor(NewDefinedLanguages(), parOpts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	if !reflect.DeepEqual(seq.Total, par.Total) {
+		t.Errorf("invalid logic. total seq=%v par=%v", seq.Total, par.Total)
+	}
+	if !reflect.DeepEqual(seq.Files, par.Files) {
+		t.Errorf("invalid logic. files differ")
+	}
+	if !reflect.DeepEqual(seq.Languages, par.Languages) {
+		t.Errorf("invalid logic. languages differ")
+	}
+	if seq.Total.Total != 40 || seq.Total.Code != 80 || seq.Total.Comments != 100 || seq.Total.Blanks != 40 {
+		t.Errorf("invalid logic. total=%v", seq.Total)
+	}
+}
+
+func TestAnalyzeParallelCallbacks(t *testing.T) {
+	dir := writeTestTree(t)
+
+	var code, comment, blank int64
+	opts := NewClocOptions()
+	opts.Jobs = 4
+	opts.OnCode = func(string) { atomic.AddInt64(&code, 1) }
+	opts.OnComment = func(string) { atomic.AddInt64(&comment, 1) }
+	opts.OnBlank = func(string) { atomic.AddInt64(&blank, 1) }
+
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if code != int64(result.Total.Code) || comment != int64(result.Total.Comments) || blank != int64(result.Total.Blanks) {
+		t.Errorf("invalid logic. code=%v comment=%v blank=%v total=%v", code, comment, blank, result.Total)
+	}
+}
+
+func TestAnalyzeStream(t *testing.T) {
+	dir := writeTestTree(t)
+
+	opts := NewClocOptions()
+	opts.Jobs = 4
+	files := make(chan *ClocFile)
+	streamed := map[string]*ClocFile{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for cf := range files {
+			streamed[cf.Name] = cf
+		}
+	}()
+
+	result, err := NewProcessor(NewDefinedLanguages(), opts).AnalyzeStream([]string{dir}, files)
+	<-done
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if !reflect.DeepEqual(streamed, result.Files) {
+		t.Errorf("invalid logic. streamed=%d files=%d", len(streamed), len(result.Files))
+	}
+
+	expected, err := NewProcessor(NewDefinedLanguages(), NewClocOptions()).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if !reflect.DeepEqual(expected.Total, result.Total) || !reflect.DeepEqual(expected.Languages, result.Languages) {
+		t.Errorf("invalid logic. total=%v expected=%v", result.Total, expected.Total)
+	}
+}
+
+func TestAnalyzeContextCanceled(t *testing.T) {
+	dir := writeTestTree(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	opts := NewClocOptions()
+	opts.Jobs = 4
+	result, err := NewProcessor(NewDefinedLanguages(), opts).AnalyzeContext(ctx, []string{dir})
+	if err != context.Canceled {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+	if result == nil {
+		t.Fatalf("invalid logic. partial result is nil")
+	}
+	if len(result.Files) != 0 || result.Total.Total != 0 {
+		t.Errorf("invalid logic. files=%v total=%v", len(result.Files), result.Total)
+	}
+}
+
+func TestAnalyzeContextPartial(t *testing.T) {
+	dir := writeTestTree(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lines int64
+	opts := NewClocOptions()
+	opts.OnCode = func(string) {
+		// cancel after a few files have been counted
+		if atomic.AddInt64(&lines, 1) == 11 {
+			cancel()
+		}
+	}
+	result, err := NewProcessor(NewDefinedLanguages(), opts).AnalyzeContext(ctx, []string{dir})
+	if err != context.Canceled {
+		t.Errorf("invalid logic. err=[%v]", err)
+	}
+
+	var files int32
+	for _, lang := range result.Languages {
+		files += int32(len(lang.Files))
+		for _, file := range lang.Files {
+			if _, ok := result.Files[file]; !ok {
+				t.Errorf("invalid logic. %v is not counted", file)
+			}
+		}
+	}
+	if files != result.Total.Total || int(files) != len(result.Files) {
+		t.Errorf("invalid logic. files=%v total=%v clocFiles=%v", files, result.Total.Total, len(result.Files))
+	}
+	if files == 0 || files >= 40 {
+		t.Errorf("invalid logic. result is not partial. files=%v", files)
+	}
+}
+
+func TestAnalyzeWithMemMapFs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/repo/.gitignore":       "gen/\n",
+		"/repo/main.go":          "package main\n\n// main\nfunc main() {}\n",
+		"/repo/copy.go":          "package main\n\n// main\nfunc main() {}\n",
+		"/repo/tools/run":        "#!/usr/bin/env python\nprint(1)\n",
+		"/repo/gen/generated.go": "package gen\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := NewClocOptions()
+	opts.Fs = fs
+	result, err := NewProcessor(NewDefinedLanguages(), opts).Analyze([]string{"/repo"})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+
+	if lang, ok := result.Languages["Go"]; !ok || len(lang.Files) != 1 || lang.Code != 2 || lang.Comments != 1 || lang.Blanks != 1 {
+		t.Errorf("invalid logic. go=%v", lang)
+	}
+	if lang, ok := result.Languages["Python"]; !ok || len(lang.Files) != 1 || lang.Code != 2 {
+		t.Errorf("invalid logic. python=%v", lang)
+	}
+	if result.Total.Total != 2 {
+		t.Errorf("invalid logic. total=%v", result.Total)
+	}
+}
@@ -0,0 +1,344 @@
+package gocloc
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// GoclocIgnoreFile is the name of gocloc's own ignore file, it uses the .gitignore syntax.
+const GoclocIgnoreFile = ".gocloc-ignore"
+
+// ignoreFiles are read in each walked directory, the first one has the highest precedence.
+var ignoreFiles = []string{GoclocIgnoreFile, ".gitignore"}
+
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are the patterns of one ignore file, relative to base.
+type ignoreRules struct {
+	base     string
+	patterns []ignorePattern
+}
+
+// compileIgnorePattern converts one line of an ignore file into a pattern.
+func compileIgnorePattern(line string) (ignorePattern, bool) {
+	p := ignorePattern{}
+
+	// trailing spaces are ignored unless they are escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return p, false
+	}
+	if line[0] == '!' {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+
+	var buf strings.Builder
+	// a slash at the beginning or in the middle anchors the pattern to the ignore file's directory
+	if strings.Contains(line, "/") {
+		buf.WriteString("^")
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		buf.WriteString("(?:^|/)")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/") && (i == 0 || line[i-1] == '/'):
+			buf.WriteString("(?:.*/)?")
+			i += 2
+		case line[i:] == "**" && (i == 0 || line[i-1] == '/'):
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			buf.WriteString(regexp.QuoteMeta(line[i+1 : i+2]))
+			i++
+		default:
+			buf.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		}
+	}
+	buf.WriteString("$")
+
+	re, err := regexp.Compile(buf.String())
+	if err != nil {
+		return p, false
+	}
+	p.re = re
+	return p, true
+}
+
+// readIgnoreRules reads the ignore file at path, a missing file returns nil.
+func readIgnoreRules(fs afero.Fs, path, base string) *ignoreRules {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	rules := &ignoreRules{base: base}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if p, ok := compileIgnorePattern(scanner.Text()); ok {
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+	if len(rules.patterns) == 0 {
+		return nil
+	}
+	return rules
+}
+
+// match reports whether absPath is ignored (or re-included by a negation) by rules.
+func (rules *ignoreRules) match(absPath string, isDir bool) (ignored, matched bool) {
+	rel, err := filepath.Rel(rules.base, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// the last matching pattern decides
+	for i := len(rules.patterns) - 1; i >= 0; i-- {
+		p := rules.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			return !p.negate, true
+		}
+	}
+	return false, false
+}
+
+// ignoreMatcher applies the ignore files while one root is walked.
+type ignoreMatcher struct {
+	fs      afero.Fs
+	root    string
+	absRoot string
+	// outer are the rules that do not belong to a walked directory, highest precedence first.
+	outer []*ignoreRules
+	// dirs are the rules of the walked directories, keyed by absolute path.
+	dirs map[string][]*ignoreRules
+}
+
+// newIgnoreMatcher prepares the ignore rules for walking root. Inside a git repository, the
+// ignore files of the directories between the repository and root, .git/info/exclude and the
+// global excludes file apply as well. For a GitRevisionFs, the ignore files of the directories
+// are those of the revision, and the others are read from the repository on the host.
+func newIgnoreMatcher(fs afero.Fs, root string) *ignoreMatcher {
+	absRoot := filepath.Clean(root)
+	var repoRoot, excludeFile string
+	onHost := false
+	switch gfs := fs.(type) {
+	case *afero.OsFs:
+		var err error
+		if absRoot, err = filepath.Abs(root); err != nil {
+			return nil
+		}
+		onHost = true
+	case *gitRevisionFs:
+		if !filepath.IsAbs(absRoot) {
+			absRoot = filepath.Join(gfs.dir, absRoot)
+		}
+		repoRoot = gfs.root
+		excludeFile = filepath.Join(gfs.repo.commonDir, "info", "exclude")
+		onHost = true
+	}
+	m := &ignoreMatcher{
+		fs:      fs,
+		root:    root,
+		absRoot: absRoot,
+		dirs:    make(map[string][]*ignoreRules),
+	}
+
+	if repoRoot == "" {
+		var ok bool
+		if repoRoot, ok = findGitRepository(fs, absRoot); !ok {
+			return m
+		}
+		excludeFile = filepath.Join(repoRoot, ".git", "info", "exclude")
+	}
+	if absRoot != repoRoot {
+		for dir := filepath.Dir(absRoot); ; dir = filepath.Dir(dir) {
+			m.outer = append(m.outer, loadDirIgnoreRules(fs, dir)...)
+			if dir == repoRoot || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+	hostFs := fs
+	if onHost {
+		hostFs = afero.NewOsFs()
+	}
+	if rules := readIgnoreRules(hostFs, excludeFile, repoRoot); rules != nil {
+		m.outer = append(m.outer, rules)
+	}
+	// the git configuration and the global excludes file are files of the host,
+	// they do not apply to the other file systems
+	if onHost {
+		if excludesFile := globalExcludesFile(repoRoot); excludesFile != "" {
+			if rules := readIgnoreRules(hostFs, excludesFile, repoRoot); rules != nil {
+				m.outer = append(m.outer, rules)
+			}
+		}
+	}
+	return m
+}
+
+func loadDirIgnoreRules(fs afero.Fs, dir string) []*ignoreRules {
+	var rules []*ignoreRules
+	for _, name := range ignoreFiles {
+		if r := readIgnoreRules(fs, filepath.Join(dir, name), dir); r != nil {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func (m *ignoreMatcher) abs(path string) string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(m.absRoot, rel)
+}
+
+// loadDir reads the ignore files of a walked directory.
+func (m *ignoreMatcher) loadDir(path string) {
+	abs := m.abs(path)
+	if rules := loadDirIgnoreRules(m.fs, abs); len(rules) > 0 {
+		m.dirs[abs] = rules
+	}
+}
+
+// ignored reports whether path found by the walk is excluded by the ignore files.
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	abs := m.abs(path)
+	if abs == m.absRoot {
+		// paths given explicitly are never ignored
+		return false
+	}
+
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		for _, rules := range m.dirs[dir] {
+			if ignored, ok := rules.match(abs, isDir); ok {
+				return ignored
+			}
+		}
+		if dir == m.absRoot || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	for _, rules := range m.outer {
+		if ignored, ok := rules.match(abs, isDir); ok {
+			return ignored
+		}
+	}
+	return false
+}
+
+// findGitRepository returns the work tree root containing dir.
+func findGitRepository(fs afero.Fs, dir string) (string, bool) {
+	for {
+		if _, err := fs.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// globalExcludesFile returns core.excludesFile of the git configuration, or git's default.
+func globalExcludesFile(repoRoot string) string {
+	home, _ := os.UserHomeDir()
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" && home != "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+
+	configs := []string{filepath.Join(repoRoot, ".git", "config")}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	if xdgConfig != "" {
+		configs = append(configs, filepath.Join(xdgConfig, "git", "config"))
+	}
+	for _, config := range configs {
+		if path := readExcludesFile(config); path != "" {
+			if strings.HasPrefix(path, "~/") && home != "" {
+				path = filepath.Join(home, path[2:])
+			}
+			return path
+		}
+	}
+
+	if xdgConfig != "" {
+		return filepath.Join(xdgConfig, "git", "ignore")
+	}
+	return ""
+}
+
+// readExcludesFile returns the excludesfile entry of the [core] section of a git config file.
+func readExcludesFile(config string) string {
+	f, err := os.Open(config)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	inCore := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inCore = strings.EqualFold(strings.Trim(line, "[] \t"), "core")
+			continue
+		}
+		if !inCore {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package gocloc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestCompileIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"build/", "build", true, true},
+		{"build/", "src/build", true, true},
+		{"build/", "build", false, false},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/arch.txt", false, false},
+		{"**/foo", "foo", true, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"abc/**", "abc/x/y.go", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"file?.go", "file1.go", false, true},
+		{"file[0-9].go", "file7.go", false, true},
+		{"file[!0-9].go", "file7.go", false, false},
+		{`\#hash`, "#hash", false, true},
+		{"trailing  ", "trailing", false, true},
+	}
+	for _, tt := range tests {
+		p, ok := compileIgnorePattern(tt.pattern)
+		if !ok {
+			t.Errorf("invalid logic. pattern=[%v] is not compiled", tt.pattern)
+			continue
+		}
+		rules := &ignoreRules{base: "/repo", patterns: []ignorePattern{p}}
+		ignored, _ := rules.match(filepath.Join("/repo", filepath.FromSlash(tt.path)), tt.isDir)
+		if ignored != tt.match {
+			t.Errorf("invalid logic. pattern=[%v] path=[%v] ignored=%v", tt.pattern, tt.path, ignored)
+		}
+	}
+
+	for _, line := range []string{"", "# comment", "   ", "!"} {
+		if _, ok := compileIgnorePattern(line); ok {
+			t.Errorf("invalid logic. line=[%v] must be skipped", line)
+		}
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collectFiles(t *testing.T, root string, opts *ClocOptions) []string {
+	languages, err := getAllFiles(context.Background(), []string{root}, NewDefinedLanguages(), opts, nil)
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	var files []string
+	for _, lang := range languages {
+		for _, file := range lang.Files {
+			rel, _ := filepath.Rel(root, file)
+			files = append(files, filepath.ToSlash(rel))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestGetAllFilesWithIgnoreFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	writeTestFiles(t, home, map[string]string{
+		".config/git/ignore": "*.tmp.go\n",
+	})
+
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".git/info/exclude":         "local.go\n",
+		".gitignore":                "node_modules/\n*.gen.go\n!keep.gen.go\n/out\n",
+		".gocloc-ignore":            "fixtures/\n",
+		"main.go":                   "package main // main.go\n",
+		"local.go":                  "package main // local.go\n",
+		"cache.tmp.go":              "package main // cache.tmp.go\n",
+		"api.gen.go":                "package main // api.gen.go\n",
+		"keep.gen.go":               "package main // keep.gen.go\n",
+		"node_modules/lib/index.js": "var a = \"node_modules/lib/index.js\";\n",
+		"out/bundle.js":             "var a = \"out/bundle.js\";\n",
+		"src/out/main.js":           "var a = \"src/out/main.js\";\n",
+		"src/.gitignore":            "*.js\n!main.js\n",
+		"src/app.js":                "var a = \"src/app.js\";\n",
+		"fixtures/data.py":          "x = 1\n",
+	})
+
+	opts := NewClocOptions()
+	files := collectFiles(t, root, opts)
+	expected := "keep.gen.go,main.go,src/out/main.js"
+	if strings.Join(files, ",") != expected {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	// a subdirectory of the repository still honors the parent ignore files
+	files = collectFiles(t, filepath.Join(root, "src"), opts)
+	if strings.Join(files, ",") != "out/main.js" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	opts.NoIgnore = true
+	files = collectFiles(t, root, opts)
+	if len(files) != 10 {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
+
+func TestGetAllFilesIgnoresHostExcludesOnOtherFs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	writeTestFiles(t, home, map[string]string{
+		".config/git/ignore": "*.tmp.go\n",
+	})
+
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/repo/.git/info/exclude": "local.go\n",
+		"/repo/main.go":           "package main // main.go\n",
+		"/repo/local.go":          "package main // local.go\n",
+		"/repo/cache.tmp.go":      "package main // cache.tmp.go\n",
+		// the same path as the host file, read from the other file system
+		filepath.Join(home, ".config/git/ignore"): "main.go\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := NewClocOptions()
+	opts.Fs = fs
+	if files := collectFiles(t, "/repo", opts); strings.Join(files, ",") != "cache.tmp.go,main.go" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
@@ -2,14 +2,16 @@ package gocloc
 
 // JSONLanguagesResult defines the result of the analysis in JSON format.
 type JSONLanguagesResult struct {
-	Languages []ClocLanguage `json:"languages"`
-	Total     ClocLanguage   `json:"total"`
+	Languages  []ClocLanguage  `json:"languages"`
+	Total      ClocLanguage    `json:"total"`
+	Duplicates *JSONDuplicates `json:"duplicates,omitempty"`
 }
 
 // JSONFilesResult defines the result of the analysis(by files) in JSON format.
 type JSONFilesResult struct {
-	Files []ClocFile   `json:"files"`
-	Total ClocLanguage `json:"total"`
+	Files      []ClocFile      `json:"files"`
+	Total      ClocLanguage    `json:"total"`
+	Duplicates *JSONDuplicates `json:"duplicates,omitempty"`
 }
 
 // NewJSONLanguagesResultFromCloc returns JSONLanguagesResult with default data set.
@@ -17,19 +19,24 @@ func NewJSONLanguagesResultFromCloc(total *Language, sortedLanguages Languages)
 	var langs []ClocLanguage
 	for _, language := range sortedLanguages {
 		c := ClocLanguage{
-			Name:       language.Name,
-			FilesCount: int32(len(language.Files)),
-			Code:       language.Code,
-			Comments:   language.Comments,
-			Blanks:     language.Blanks,
+			Name:        language.Name,
+			FilesCount:  int32(len(language.Files)),
+			Code:        language.Code,
+			Comments:    language.Comments,
+			DocComments: language.DocComments,
+			Blanks:      language.Blanks,
+			Mixed:       language.Mixed,
+			Embedded:    embeddedClocLanguages(&language),
 		}
 		langs = append(langs, c)
 	}
 	t := ClocLanguage{
-		FilesCount: total.Total,
-		Code:       total.Code,
-		Comments:   total.Comments,
-		Blanks:     total.Blanks,
+		FilesCount:  total.Total,
+		Code:        total.Code,
+		Comments:    total.Comments,
+		DocComments: total.DocComments,
+		Blanks:      total.Blanks,
+		Mixed:       total.Mixed,
 	}
 
 	return JSONLanguagesResult{
@@ -41,10 +48,12 @@ func NewJSONLanguagesResultFromCloc(total *Language, sortedLanguages Languages)
 // NewJSONFilesResultFromCloc returns JSONFilesResult with default data set.
 func NewJSONFilesResultFromCloc(total *Language, sortedFiles ClocFiles) JSONFilesResult {
 	t := ClocLanguage{
-		FilesCount: total.Total,
-		Code:       total.Code,
-		Comments:   total.Comments,
-		Blanks:     total.Blanks,
+		FilesCount:  total.Total,
+		Code:        total.Code,
+		Comments:    total.Comments,
+		DocComments: total.DocComments,
+		Blanks:      total.Blanks,
+		Mixed:       total.Mixed,
 	}
 
 	return JSONFilesResult{
@@ -52,3 +61,87 @@ func NewJSONFilesResultFromCloc(total *Language, sortedFiles ClocFiles) JSONFile
 		Total: t,
 	}
 }
+
+// JSONDiffLanguagesResult defines the result of Processor.Diff in JSON format.
+type JSONDiffLanguagesResult struct {
+	Languages []ClocDiffLanguage `json:"languages"`
+	Total     ClocDiffLanguage   `json:"total"`
+}
+
+// JSONDiffFilesResult defines the result of Processor.Diff(by files) in JSON format.
+type JSONDiffFilesResult struct {
+	Files []ClocDiffFile   `json:"files"`
+	Total ClocDiffLanguage `json:"total"`
+}
+
+// NewJSONDiffLanguagesResult returns JSONDiffLanguagesResult with the languages sorted by name.
+func NewJSONDiffLanguagesResult(result *DiffResult) JSONDiffLanguagesResult {
+	t := *result.Total
+	t.Name = ""
+	return JSONDiffLanguagesResult{
+		Languages: result.SortedDiffLanguages(),
+		Total:     t,
+	}
+}
+
+// NewJSONDiffFilesResult returns JSONDiffFilesResult with the files sorted by name.
+func NewJSONDiffFilesResult(result *DiffResult) JSONDiffFilesResult {
+	t := *result.Total
+	t.Name = ""
+	return JSONDiffFilesResult{
+		Files: result.SortedDiffFiles(),
+		Total: t,
+	}
+}
+
+// JSONDuplicateFile is a counted file and its copies in JSON format.
+type JSONDuplicateFile struct {
+	Name   string   `json:"name"`
+	Lang   string   `json:"language"`
+	Copies []string `json:"copies"`
+}
+
+// JSONDuplicates defines the files skipped as duplicates in JSON format.
+type JSONDuplicates struct {
+	Files     []JSONDuplicateFile `json:"files"`
+	Languages []ClocLanguage      `json:"languages"`
+	Total     ClocLanguage        `json:"total"`
+}
+
+// NewJSONDuplicates returns JSONDuplicates of the Duplicates of result, sorted by name.
+func NewJSONDuplicates(result *Result) *JSONDuplicates {
+	d := result.Duplicates
+	files := []JSONDuplicateFile{}
+	for _, original := range d.SortedOriginals() {
+		f := JSONDuplicateFile{Name: original, Copies: d.Files[original]}
+		if cf, ok := result.Files[original]; ok {
+			f.Lang = cf.Lang
+		}
+		files = append(files, f)
+	}
+	return &JSONDuplicates{
+		Files:     files,
+		Languages: d.SortedLanguages(),
+		Total:     *d.Total,
+	}
+}
+
+// JSONCPDResult defines the result of Processor.DetectClones in JSON format.
+type JSONCPDResult struct {
+	Clones    []CloneGroup  `json:"clones"`
+	Languages []CPDLanguage `json:"languages"`
+	Total     CPDLanguage   `json:"total"`
+}
+
+// NewJSONCPDResult returns JSONCPDResult with the languages sorted by name.
+func NewJSONCPDResult(result *CPDResult) JSONCPDResult {
+	clones := result.Clones
+	if clones == nil {
+		clones = []CloneGroup{}
+	}
+	return JSONCPDResult{
+		Clones:    clones,
+		Languages: result.SortedLanguages(),
+		Total:     *result.Total,
+	}
+}
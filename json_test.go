@@ -36,3 +36,22 @@ func TestOutputJSON(t *testing.T) {
 		t.Errorf("invalid result. '%s'", resultJSONText)
 	}
 }
+
+func TestOutputJSONWithMixed(t *testing.T) {
+	total := &Language{Total: 1, Code: 3, Mixed: 2}
+	languages := []Language{
+		{Name: "Go", Files: []string{"one.go"}, Code: 3, Mixed: 2},
+	}
+	jsonResult := NewJSONLanguagesResultFromCloc(total, languages)
+
+	buf, err := json.Marshal(jsonResult)
+	if err != nil {
+		t.Fatalf("json marshal error. err=[%v]", err)
+	}
+
+	actualJSONText := `{"languages":[{"name":"Go","files":1,"code":3,"comment":0,"blank":0,"mixed":2}],"total":{"files":1,"code":3,"comment":0,"blank":0,"mixed":2}}`
+	resultJSONText := string(buf)
+	if actualJSONText != resultJSONText {
+		t.Errorf("invalid result. '%s'", resultJSONText)
+	}
+}
@@ -0,0 +1,357 @@
+package gocloc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LanguageDefinition is a user-defined language read from a definition file.
+type LanguageDefinition struct {
+	Name         string     `json:"-" yaml:"-"`
+	Extensions   []string   `json:"extensions" yaml:"extensions"`
+	Filenames    []string   `json:"filenames" yaml:"filenames"`
+	Interpreters []string   `json:"interpreters" yaml:"interpreters"`
+	LineComments []string   `json:"line_comments" yaml:"line_comments"`
+	MultiLines   [][]string `json:"multi_line_comments" yaml:"multi_line_comments"`
+	// NestedComments are the begin markers of the multi-line comments that nest.
+	NestedComments []string `json:"nested_comments" yaml:"nested_comments"`
+	// StringLiterals are the string literals, comment markers inside them are code.
+	StringLiterals []StringLiteral `json:"string_literals" yaml:"string_literals"`
+	// DocLineComments and DocMultiLines are the markers of the documentation comments,
+	// DocDeclaration is a regular expression of the declarations that make the comment
+	// lines before them documentation.
+	DocLineComments []string `json:"doc_line_comments" yaml:"doc_line_comments"`
+	DocMultiLines   []string `json:"doc_multi_line_comments" yaml:"doc_multi_line_comments"`
+	DocDeclaration  string   `json:"doc_declaration" yaml:"doc_declaration"`
+}
+
+// LanguageConflict describes a user definition that collides with an existing one.
+type LanguageConflict struct {
+	// Kind is one of "language", "extension", "filename" or "interpreter".
+	Kind     string
+	Key      string
+	Existing string
+	Defined  string
+}
+
+func (c LanguageConflict) String() string {
+	if c.Kind == "language" {
+		return fmt.Sprintf("language %q is already defined", c.Key)
+	}
+	return fmt.Sprintf("%s %q is already mapped to %q (defined for %q)", c.Kind, c.Key, c.Existing, c.Defined)
+}
+
+// ReadLanguageDefinitionFile reads language definitions from path.
+// Files with a .json extension are decoded as JSON, files with a .yaml or .yml extension as YAML,
+// everything else is parsed as cloc's --write-lang-def format.
+func ReadLanguageDefinitionFile(path string) ([]LanguageDefinition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ReadLanguageDefinitionsJSON(f)
+	case ".yaml", ".yml":
+		return ReadLanguageDefinitionsYAML(f)
+	}
+	return ReadLanguageDefinitionsCloc(f)
+}
+
+// ReadLanguageDefinitionsJSON decodes definitions from an object keyed by language name.
+//
+//	{"MyDSL": {"extensions": ["dsl"], "line_comments": ["#"], "multi_line_comments": [["/*", "*/"]]}}
+func ReadLanguageDefinitionsJSON(r io.Reader) ([]LanguageDefinition, error) {
+	var m map[string]LanguageDefinition
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid language definition: %w", err)
+	}
+	return namedLanguageDefinitions(m)
+}
+
+// ReadLanguageDefinitionsYAML decodes definitions from a mapping keyed by language name,
+// with the keys of ReadLanguageDefinitionsJSON.
+//
+//	MyDSL:
+//	  extensions: [dsl]
+//	  line_comments: ["#"]
+//	  multi_line_comments:
+//	    - ["/*", "*/"]
+func ReadLanguageDefinitionsYAML(r io.Reader) ([]LanguageDefinition, error) {
+	var m map[string]LanguageDefinition
+	if err := yaml.NewDecoder(r).Decode(&m); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid language definition: %w", err)
+	}
+	return namedLanguageDefinitions(m)
+}
+
+// namedLanguageDefinitions returns the definitions of m named by their keys, sorted by name.
+func namedLanguageDefinitions(m map[string]LanguageDefinition) ([]LanguageDefinition, error) {
+	defs := make([]LanguageDefinition, 0, len(m))
+	for name, def := range m {
+		def.Name = name
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	for _, def := range defs {
+		if err := def.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return defs, nil
+}
+
+// regexpCommon is the comment syntax of cloc's "call_regexp_common" filters.
+var regexpCommon = map[string]struct {
+	lineComments []string
+	multiLines   [][]string
+}{
+	"C":       {nil, [][]string{{"/*", "*/"}}},
+	"C++":     {[]string{"//"}, [][]string{{"/*", "*/"}}},
+	"HTML":    {nil, [][]string{{"<!--", "-->"}}},
+	"Haskell": {[]string{"--"}, [][]string{{"{-", "-}"}}},
+	"lua":     {[]string{"--"}, [][]string{{"--[[", "]]"}}},
+	"Pascal":  {nil, [][]string{{"{", "}"}, {"(*", "*)"}}},
+}
+
+// ReadLanguageDefinitionsCloc parses the text format written by cloc --write-lang-def.
+// Only filters that map onto line comments and comment pairs are honored, the rest are ignored.
+func ReadLanguageDefinitionsCloc(r io.Reader) ([]LanguageDefinition, error) {
+	var defs []LanguageDefinition
+	var cur *LanguageDefinition
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			defs = append(defs, LanguageDefinition{Name: strings.TrimSpace(line)})
+			cur = &defs[len(defs)-1]
+			continue
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("invalid language definition: line %d: no language name", lineNo)
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "extension":
+			cur.Extensions = append(cur.Extensions, fields[1:]...)
+		case "filename":
+			cur.Filenames = append(cur.Filenames, fields[1:]...)
+		case "script_exe":
+			cur.Interpreters = append(cur.Interpreters, fields[1:]...)
+		case "filter":
+			if len(fields) < 2 {
+				continue
+			}
+			args := fields[2:]
+			switch fields[1] {
+			case "remove_matches":
+				if len(args) == 1 {
+					if comm, ok := lineCommentFromRegexp(args[0]); ok {
+						cur.LineComments = append(cur.LineComments, comm)
+					}
+				}
+			case "remove_between_general":
+				if len(args) == 2 {
+					cur.MultiLines = append(cur.MultiLines, []string{args[0], args[1]})
+				}
+			case "remove_between_regex":
+				if len(args) == 2 {
+					begin, okBegin := literalFromRegexp(args[0])
+					end, okEnd := literalFromRegexp(args[1])
+					if okBegin && okEnd {
+						cur.MultiLines = append(cur.MultiLines, []string{begin, end})
+					}
+				}
+			case "call_regexp_common":
+				if len(args) == 1 {
+					if common, ok := regexpCommon[args[0]]; ok {
+						cur.LineComments = append(cur.LineComments, common.lineComments...)
+						cur.MultiLines = append(cur.MultiLines, common.multiLines...)
+					}
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, def := range defs {
+		if err := def.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return defs, nil
+}
+
+// lineCommentFromRegexp extracts the comment marker from filters such as `^\s*#`.
+func lineCommentFromRegexp(expr string) (string, bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 {
+		return "", false
+	}
+
+	subs := re.Sub
+	if subs[0].Op != syntax.OpBeginLine && subs[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+	subs = subs[1:]
+	if len(subs) > 0 && (subs[0].Op == syntax.OpStar || subs[0].Op == syntax.OpPlus) {
+		subs = subs[1:]
+	}
+	if len(subs) != 1 || subs[0].Op != syntax.OpLiteral {
+		return "", false
+	}
+	return string(subs[0].Rune), true
+}
+
+// literalFromRegexp returns expr unescaped when it matches a fixed string only.
+func literalFromRegexp(expr string) (string, bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpLiteral {
+		return "", false
+	}
+	return string(re.Rune), true
+}
+
+func (def *LanguageDefinition) validate() error {
+	if def.Name == "" {
+		return fmt.Errorf("invalid language definition: empty language name")
+	}
+	for _, ml := range def.MultiLines {
+		if len(ml) != 2 || ml[0] == "" || ml[1] == "" {
+			return fmt.Errorf("invalid language definition: %s: multi line comment must be a begin/end pair", def.Name)
+		}
+	}
+	for _, begin := range def.NestedComments {
+		nested := false
+		for _, ml := range def.MultiLines {
+			nested = nested || (len(ml) == 2 && ml[0] == begin)
+		}
+		if !nested {
+			return fmt.Errorf("invalid language definition: %s: nested comment %q is not a multi line comment", def.Name, begin)
+		}
+	}
+	for _, sl := range def.StringLiterals {
+		if sl.Begin == "" || sl.End == "" {
+			return fmt.Errorf("invalid language definition: %s: string literal must have a begin and an end", def.Name)
+		}
+	}
+	if def.DocDeclaration != "" {
+		if _, err := regexp.Compile(def.DocDeclaration); err != nil {
+			return fmt.Errorf("invalid language definition: %s: doc declaration: %w", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// language returns the Language of def. An invalid DocDeclaration is ignored, see validate.
+func (def *LanguageDefinition) language() *Language {
+	multiLines := def.MultiLines
+	if len(multiLines) == 0 {
+		multiLines = [][]string{{"", ""}}
+	}
+	lineComments := def.LineComments
+	if lineComments == nil {
+		lineComments = []string{}
+	}
+	docs := DocComments{LineComments: def.DocLineComments, MultiLines: def.DocMultiLines}
+	if def.DocDeclaration != "" {
+		docs.Declaration, _ = regexp.Compile(def.DocDeclaration)
+	}
+	language := NewLanguage(def.Name, lineComments, multiLines).
+		WithNestedComments(def.NestedComments...).
+		WithDocComments(docs)
+	if len(def.StringLiterals) > 0 {
+		language.WithStringLiterals(def.StringLiterals...)
+	}
+	return language
+}
+
+// Merge adds defs to langs and registers their extensions, file names and shebang
+// interpreters in the lookup tables of langs, other DefinedLanguages are not changed.
+// Without force, built-in definitions take precedence and every collision is returned
+// as a LanguageConflict; with force, the user definitions override them.
+func (langs *DefinedLanguages) Merge(defs []LanguageDefinition, force bool) []LanguageConflict {
+	var conflicts []LanguageConflict
+	langs.ownLookupTables()
+
+	for _, def := range defs {
+		if _, ok := langs.Langs[def.Name]; ok {
+			conflicts = append(conflicts, LanguageConflict{Kind: "language", Key: def.Name, Existing: def.Name, Defined: def.Name})
+			if !force {
+				continue
+			}
+		}
+
+		langs.Langs[def.Name] = def.language()
+
+		// resolve the current owners of the interpreters first, they fall back to the extensions
+		interpOwners := make([]string, len(def.Interpreters))
+		for i, interp := range def.Interpreters {
+			interpOwners[i], _ = langs.interpreterLanguage(interp)
+		}
+
+		for _, ext := range def.Extensions {
+			ext = strings.TrimPrefix(ext, ".")
+			if name, ok := langs.exts[ext]; ok && name != def.Name {
+				conflicts = append(conflicts, LanguageConflict{Kind: "extension", Key: ext, Existing: name, Defined: def.Name})
+				if !force {
+					continue
+				}
+			}
+			langs.exts[ext] = def.Name
+		}
+
+		for _, filename := range def.Filenames {
+			if owner, ok := langs.filenames[filename]; ok && owner != def.Name {
+				conflicts = append(conflicts, LanguageConflict{Kind: "filename", Key: filename, Existing: owner, Defined: def.Name})
+				if !force {
+					continue
+				}
+			}
+			langs.filenames[filename] = def.Name
+		}
+
+		for i, interp := range def.Interpreters {
+			if owner := interpOwners[i]; owner != "" && owner != def.Name {
+				conflicts = append(conflicts, LanguageConflict{Kind: "interpreter", Key: interp, Existing: owner, Defined: def.Name})
+				if !force {
+					continue
+				}
+			}
+			langs.interpreters[interp] = def.Name
+		}
+	}
+
+	return conflicts
+}
@@ -0,0 +1,239 @@
+package gocloc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestReadLanguageDefinitionsJSON(t *testing.T) {
+	defs, err := ReadLanguageDefinitionsJSON(strings.NewReader(`{
+		"QueryLang": {
+			"extensions": ["qlang", ".ql2"],
+			"filenames": ["Queryfile"],
+			"interpreters": ["qlang"],
+			"line_comments": ["--"],
+			"multi_line_comments": [["{*", "*}"]]
+		},
+		"ConfLang": {"extensions": ["conf2"], "line_comments": [";"]}
+	}`))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("invalid logic. defs=%v", defs)
+	}
+	if defs[0].Name != "ConfLang" || defs[1].Name != "QueryLang" {
+		t.Errorf("invalid logic. names=[%v, %v]", defs[0].Name, defs[1].Name)
+	}
+	if defs[1].MultiLines[0][0] != "{*" || defs[1].MultiLines[0][1] != "*}" {
+		t.Errorf("invalid logic. multiLines=%v", defs[1].MultiLines)
+	}
+}
+
+func TestReadLanguageDefinitionsJSONInvalid(t *testing.T) {
+	if _, err := ReadLanguageDefinitionsJSON(strings.NewReader(`{"Bad": {"multi_line_comments": [["/*"]]}}`)); err == nil {
+		t.Errorf("invalid logic. unpaired multi line comment must be an error")
+	}
+	if _, err := ReadLanguageDefinitionsJSON(strings.NewReader(`[`)); err == nil {
+		t.Errorf("invalid logic. broken json must be an error")
+	}
+}
+
+func TestReadLanguageDefinitionsYAML(t *testing.T) {
+	defs, err := ReadLanguageDefinitionsYAML(strings.NewReader(`
+QueryLang:
+  extensions:
+    - qlang
+  filenames: [Queryfile]
+  line_comments:
+    - "--"
+  multi_line_comments:
+    - ["{*", "*}"]
+ConfLang:
+  extensions: [conf2]
+  line_comments: [";"]
+`))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(defs) != 2 || defs[0].Name != "ConfLang" || defs[1].Name != "QueryLang" {
+		t.Fatalf("invalid logic. defs=%v", defs)
+	}
+	q := defs[1]
+	if len(q.Extensions) != 1 || q.Extensions[0] != "qlang" || q.Filenames[0] != "Queryfile" || q.LineComments[0] != "--" {
+		t.Errorf("invalid logic. def=%v", q)
+	}
+	if len(q.MultiLines) != 1 || q.MultiLines[0][0] != "{*" || q.MultiLines[0][1] != "*}" {
+		t.Errorf("invalid logic. multiLines=%v", q.MultiLines)
+	}
+
+	if _, err := ReadLanguageDefinitionsYAML(strings.NewReader("Bad:\n  multi_line_comments: [[\"/*\"]]\n")); err == nil {
+		t.Errorf("invalid logic. unpaired multi line comment must be an error")
+	}
+}
+
+func TestReadLanguageDefinitionsCloc(t *testing.T) {
+	defs, err := ReadLanguageDefinitionsCloc(strings.NewReader(`QueryLang
+    filter remove_matches ^\s*--
+    filter remove_matches ^\s*\#
+    filter remove_inline --.*$
+    filter remove_between_general {* *}
+    extension qlang
+    filename Queryfile
+    script_exe qlang
+    3rd_gen_scale 1.00
+Templ
+    filter call_regexp_common C++
+    extension templ
+`))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("invalid logic. defs=%v", defs)
+	}
+
+	q := defs[0]
+	if q.Name != "QueryLang" {
+		t.Errorf("invalid logic. name=%v", q.Name)
+	}
+	if len(q.LineComments) != 2 || q.LineComments[0] != "--" || q.LineComments[1] != "#" {
+		t.Errorf("invalid logic. lineComments=%v", q.LineComments)
+	}
+	if len(q.MultiLines) != 1 || q.MultiLines[0][0] != "{*" || q.MultiLines[0][1] != "*}" {
+		t.Errorf("invalid logic. multiLines=%v", q.MultiLines)
+	}
+	if len(q.Extensions) != 1 || len(q.Filenames) != 1 || len(q.Interpreters) != 1 {
+		t.Errorf("invalid logic. def=%v", q)
+	}
+
+	templ := defs[1]
+	if len(templ.LineComments) != 1 || templ.LineComments[0] != "//" {
+		t.Errorf("invalid logic. lineComments=%v", templ.LineComments)
+	}
+	if len(templ.MultiLines) != 1 || templ.MultiLines[0][0] != "/*" {
+		t.Errorf("invalid logic. multiLines=%v", templ.MultiLines)
+	}
+}
+
+func TestDefinedLanguagesMerge(t *testing.T) {
+	langs := NewDefinedLanguages()
+	defs := []LanguageDefinition{
+		{Name: "QueryLang", Extensions: []string{"qlang"}, Filenames: []string{"Queryfile"}, Interpreters: []string{"qlang"}, LineComments: []string{"--"}},
+		{Name: "Snake", Extensions: []string{"py", "snk"}, Interpreters: []string{"python"}},
+		{Name: "Go", Extensions: []string{"go"}},
+	}
+	conflicts := langs.Merge(defs, false)
+
+	kinds := []string{}
+	for _, c := range conflicts {
+		kinds = append(kinds, c.Kind+":"+c.Key)
+	}
+	if strings.Join(kinds, ",") != "extension:py,interpreter:python,language:Go" {
+		t.Errorf("invalid logic. conflicts=%v", kinds)
+	}
+
+	qlang, _ := langs.ExtLanguage("qlang")
+	py, _ := langs.ExtLanguage("py")
+	snk, _ := langs.ExtLanguage("snk")
+	if qlang != "QueryLang" || py != "Python" || snk != "Snake" {
+		t.Errorf("invalid logic. qlang=%v py=%v snk=%v", qlang, py, snk)
+	}
+	if name, _, ok := langs.detectFileType("dir/Queryfile", afero.NewMemMapFs()); !ok || name != "QueryLang" {
+		t.Errorf("invalid logic. name=%v", name)
+	}
+	if name, ok := langs.interpreterLanguage("qlang"); !ok || name != "QueryLang" {
+		t.Errorf("invalid logic. shebang=%v", name)
+	}
+	if name, _ := langs.interpreterLanguage("python"); name != "Python" {
+		t.Errorf("invalid logic. shebang=%v", name)
+	}
+	if lang := langs.Langs["QueryLang"]; lang == nil || lang.lineComments[0] != "--" {
+		t.Errorf("invalid logic. lang=%v", lang)
+	}
+}
+
+func TestDefinedLanguagesMergeForce(t *testing.T) {
+	langs := NewDefinedLanguages()
+	defs := []LanguageDefinition{
+		{Name: "Snake", Extensions: []string{"py"}, Interpreters: []string{"python"}, LineComments: []string{"#"}},
+	}
+	conflicts := langs.Merge(defs, true)
+	if len(conflicts) != 2 {
+		t.Errorf("invalid logic. conflicts=%v", conflicts)
+	}
+	if py, _ := langs.ExtLanguage("py"); py != "Snake" {
+		t.Errorf("invalid logic. py=%v", py)
+	}
+	if name, _ := langs.interpreterLanguage("python"); name != "Snake" {
+		t.Errorf("invalid logic. shebang=%v", name)
+	}
+}
+
+func TestDefinedLanguagesMergeIsolated(t *testing.T) {
+	langs := NewDefinedLanguages()
+	langs.Merge([]LanguageDefinition{
+		{Name: "Snake", Extensions: []string{"py"}, Interpreters: []string{"python"}},
+		{Name: "NoExt", Filenames: []string{"Noextfile"}, Interpreters: []string{"noext"}},
+	}, true)
+
+	// a definition without extensions does not make its name an extension
+	if name, ok := langs.ExtLanguage("NoExt"); ok {
+		t.Errorf("invalid logic. NoExt extension=%v", name)
+	}
+	if name, _ := langs.filenameLanguage("Noextfile"); name != "NoExt" {
+		t.Errorf("invalid logic. filename=%v", name)
+	}
+	if name, _ := langs.interpreterLanguage("noext"); name != "NoExt" {
+		t.Errorf("invalid logic. interpreter=%v", name)
+	}
+
+	// the other definitions keep the built-in tables
+	other := NewDefinedLanguages()
+	if py, _ := other.ExtLanguage("py"); py != "Python" || Exts["py"] != "Python" {
+		t.Errorf("invalid logic. py=%v", py)
+	}
+	if name, _ := other.interpreterLanguage("python"); name != "Python" {
+		t.Errorf("invalid logic. shebang=%v", name)
+	}
+	if _, ok := other.filenameLanguage("Noextfile"); ok {
+		t.Errorf("invalid logic. Noextfile is defined")
+	}
+}
+
+func TestDefinedLanguagesMergeRules(t *testing.T) {
+	defs, err := ReadLanguageDefinitionsYAML(strings.NewReader(`
+Oxide:
+  extensions: [ox]
+  line_comments: ["//"]
+  multi_line_comments: [["/*", "*/"]]
+  nested_comments: ["/*"]
+  string_literals:
+    - {begin: '"', end: '"', escape: '\'}
+  doc_line_comments: ["///"]
+`))
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	langs := NewDefinedLanguages()
+	langs.Merge(defs, false)
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.ox": "/// doc\n/* outer /* inner */ still */\nlet s = \"// x\"; // note\nlet t = 1;\n",
+	})
+	result, err := NewProcessor(langs, NewClocOptions()).Analyze([]string{dir})
+	if err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	oxide := result.Languages["Oxide"]
+	if oxide == nil || oxide.Code != 2 || oxide.Comments != 2 || oxide.DocComments != 1 || oxide.Mixed != 1 {
+		t.Errorf("invalid logic. Oxide=%+v", oxide)
+	}
+
+	if _, err := ReadLanguageDefinitionsYAML(strings.NewReader("Bad:\n  multi_line_comments: [[\"/*\", \"*/\"]]\n  nested_comments: [\"{-\"]\n")); err == nil {
+		t.Errorf("invalid logic. nested comment without a pair must be an error")
+	}
+}
@@ -3,20 +3,27 @@ package gocloc
 import (
 	"bufio"
 	"bytes"
-	"os"
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"unicode"
+
+	"github.com/spf13/afero"
 )
 
 // ClocLanguage is provide for xml-cloc and json format.
 type ClocLanguage struct {
-	Name       string `xml:"name,attr" json:"name,omitempty"`
-	FilesCount int32  `xml:"files_count,attr" json:"files"`
-	Code       int32  `xml:"code,attr" json:"code"`
-	Comments   int32  `xml:"comment,attr" json:"comment"`
-	Blanks     int32  `xml:"blank,attr" json:"blank"`
+	Name        string `xml:"name,attr" json:"name,omitempty"`
+	FilesCount  int32  `xml:"files_count,attr" json:"files"`
+	Code        int32  `xml:"code,attr" json:"code"`
+	Comments    int32  `xml:"comment,attr" json:"comment"`
+	DocComments int32  `xml:"doc_comment,attr,omitempty" json:"doc_comment,omitempty"`
+	Blanks      int32  `xml:"blank,attr" json:"blank"`
+	Mixed       int32  `xml:"mixed,attr,omitempty" json:"mixed,omitempty"`
+	// Embedded are the languages inside the files of the language, see ClocFile.Embedded.
+	Embedded []ClocLanguage `xml:"embedded,omitempty" json:"embedded,omitempty"`
 }
 
 // Language is a type used to definitions and store statistics for one programming language.
@@ -24,11 +31,209 @@ type Language struct {
 	Name         string
 	lineComments []string
 	multiLines   [][]string
-	Files        []string
-	Code         int32
-	Comments     int32
-	Blanks       int32
-	Total        int32
+	// nestable flags the pairs of multiLines that nest, such as {- {- -} -} in Haskell.
+	nestable []bool
+	// stringLiterals are the string literals of the language, comment markers inside them are code.
+	stringLiterals []StringLiteral
+	// guessedLiterals is set while stringLiterals are the common quotes, not the literals of
+	// the language. A line comment after code then needs a space before it, as in x=1 # note.
+	guessedLiterals bool
+	// lineStartComments is set when the line comments count at the beginning of a line only,
+	// as in fixed-form FORTRAN and COBOL.
+	lineStartComments bool
+	// docs are the markers of the documentation comments.
+	docs        DocComments
+	Files       []string
+	Code        int32
+	Comments    int32
+	DocComments int32
+	Blanks      int32
+	Mixed       int32
+	Total       int32
+	// Embedded are the languages inside the files by language name, see ClocFile.Embedded.
+	Embedded map[string]*Language
+}
+
+// StringLiteral is the definition of one kind of string literal.
+type StringLiteral struct {
+	Begin string `json:"begin" yaml:"begin"`
+	End   string `json:"end" yaml:"end"`
+	// Escape is the escape character inside the literal, empty for raw strings.
+	Escape string `json:"escape,omitempty" yaml:"escape,omitempty"`
+	// MultiLine literals may continue over several lines.
+	MultiLine bool `json:"multi_line,omitempty" yaml:"multi_line,omitempty"`
+	// Char literals hold one character or one escape sequence, a lone begin marker such as
+	// the one of Rust's lifetime 'a is code.
+	Char bool `json:"char,omitempty" yaml:"char,omitempty"`
+	// closed literals begin only where the line has their end marker, so that a lone quote
+	// such as Lisp's 'sym is code.
+	closed bool
+}
+
+// quoteLiterals are the common quotes, the string literals of the languages without a definition of them.
+var quoteLiterals = []StringLiteral{
+	{Begin: `"`, End: `"`, Escape: `\`, closed: true},
+	{Begin: "'", End: "'", Escape: `\`, closed: true},
+}
+
+// guessStringLiterals returns the quotes of quoteLiterals that do not begin a comment marker,
+// such as ' in Visual Basic.
+func guessStringLiterals(lineComments []string, multiLines [][]string) []StringLiteral {
+	var literals []StringLiteral
+quotes:
+	for _, sl := range quoteLiterals {
+		for _, comm := range lineComments {
+			if strings.HasPrefix(comm, sl.Begin) {
+				continue quotes
+			}
+		}
+		for _, ml := range multiLines {
+			if strings.HasPrefix(ml[0], sl.Begin) {
+				continue quotes
+			}
+		}
+		literals = append(literals, sl)
+	}
+	return literals
+}
+
+var (
+	cStringLiterals = []StringLiteral{
+		{Begin: `"`, End: `"`, Escape: `\`},
+		{Begin: "'", End: "'", Escape: `\`},
+	}
+	goStringLiterals = []StringLiteral{
+		{Begin: `"`, End: `"`, Escape: `\`},
+		{Begin: "'", End: "'", Escape: `\`},
+		{Begin: "`", End: "`", MultiLine: true},
+	}
+	jvmStringLiterals = []StringLiteral{
+		{Begin: `"""`, End: `"""`, Escape: `\`, MultiLine: true},
+		{Begin: `"`, End: `"`, Escape: `\`},
+		{Begin: "'", End: "'", Escape: `\`},
+	}
+	jsStringLiterals = []StringLiteral{
+		{Begin: `"`, End: `"`, Escape: `\`},
+		{Begin: "'", End: "'", Escape: `\`},
+		{Begin: "`", End: "`", Escape: `\`, MultiLine: true},
+	}
+	pythonStringLiterals = []StringLiteral{
+		{Begin: `"""`, End: `"""`, Escape: `\`, MultiLine: true},
+		{Begin: "'''", End: "'''", Escape: `\`, MultiLine: true},
+		{Begin: `"`, End: `"`, Escape: `\`},
+		{Begin: "'", End: "'", Escape: `\`},
+	}
+	// rustStringLiterals put the longer begin markers first, so that r#" is not read as r followed by ".
+	rustStringLiterals = []StringLiteral{
+		{Begin: `r##"`, End: `"##`, MultiLine: true},
+		{Begin: `r#"`, End: `"#`, MultiLine: true},
+		{Begin: `r"`, End: `"`, MultiLine: true},
+		{Begin: `"`, End: `"`, Escape: `\`, MultiLine: true},
+		{Begin: "'", End: "'", Escape: `\`, Char: true},
+	}
+)
+
+// WithNestedComments marks the multi-line comments beginning with begins as nestable, such as
+// {- {- -} -} in Haskell. The other comments end at the first end marker, like /* /* */ in C.
+func (l *Language) WithNestedComments(begins ...string) *Language {
+	l.nestable = make([]bool, len(l.multiLines))
+	for i, ml := range l.multiLines {
+		for _, begin := range begins {
+			if ml[0] == begin && ml[0] != ml[1] {
+				l.nestable[i] = true
+			}
+		}
+	}
+	return l
+}
+
+// WithStringLiterals sets the string literals of the language, comment markers inside them are code.
+// The literals are matched in order, so longer begin markers have to come first.
+func (l *Language) WithStringLiterals(literals ...StringLiteral) *Language {
+	l.stringLiterals = literals
+	l.guessedLiterals = false
+	return l
+}
+
+// withLineStartComments makes the line comments count at the beginning of a line only.
+func (l *Language) withLineStartComments() *Language {
+	l.lineStartComments = true
+	return l
+}
+
+// WithDocComments sets the markers of the documentation comments of the language.
+func (l *Language) WithDocComments(docs DocComments) *Language {
+	l.docs = docs
+	return l
+}
+
+// isNestable reports whether the multi-line comment beginning with begin nests.
+func (l *Language) isNestable(begin string) bool {
+	for i, ml := range l.multiLines {
+		if ml[0] == begin && i < len(l.nestable) {
+			return l.nestable[i]
+		}
+	}
+	return false
+}
+
+// emptyCopy returns a language with the definition of l, without files and counts.
+func (l *Language) emptyCopy() *Language {
+	return &Language{
+		Name:              l.Name,
+		lineComments:      l.lineComments,
+		multiLines:        l.multiLines,
+		nestable:          l.nestable,
+		stringLiterals:    l.stringLiterals,
+		guessedLiterals:   l.guessedLiterals,
+		lineStartComments: l.lineStartComments,
+		docs:              l.docs,
+		Files:             []string{},
+	}
+}
+
+// DocComments are the markers of the documentation comments of a language.
+type DocComments struct {
+	// LineComments are the line comments of documentation, such as Rust's ///.
+	LineComments []string
+	// MultiLines are the begin markers of documentation blocks, such as Javadoc's /**.
+	MultiLines []string
+	// Declaration matches the code lines whose preceding comment lines are documentation,
+	// such as the exported declarations of Go.
+	Declaration *regexp.Regexp
+}
+
+var (
+	javadocComments       = DocComments{MultiLines: []string{"/**"}}
+	doxygenComments       = DocComments{LineComments: []string{"///", "//!"}, MultiLines: []string{"/**", "/*!"}}
+	tripleSlashDocs       = DocComments{LineComments: []string{"///"}, MultiLines: []string{"/**"}}
+	goExportedDeclaration = regexp.MustCompile(`^(?:package\s|(?:func|type|var|const)\s+(?:\([^)]*\)\s*)?\p{Lu}|\p{Lu})`)
+)
+
+// isDocLine reports whether line begins with a documentation line comment.
+func (d *DocComments) isDocLine(line string) bool {
+	for _, marker := range d.LineComments {
+		if isDocMarker(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDocBlock reports whether line begins with a documentation block marker.
+func (d *DocComments) isDocBlock(line string) bool {
+	for _, marker := range d.MultiLines {
+		if isDocMarker(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDocMarker reports whether line begins with marker not followed by its last character,
+// //// and /*** are ordinary comments.
+func isDocMarker(line, marker string) bool {
+	return strings.HasPrefix(line, marker) && !strings.HasPrefix(line[len(marker):], marker[len(marker)-1:])
 }
 
 // Languages is an array representation of Language.
@@ -52,43 +257,549 @@ var reShebangLang = regexp.MustCompile(`^#! *[.a-zA-Z/]+/([a-zA-Z]+)`)
 
 // Exts is the definition of the language name, keyed by the extension for each language.
 var Exts = map[string]string{
-	"go": "Go",
+	"abap":        "ABAP",
+	"abnf":        "ABNF",
+	"as":          "ActionScript",
+	"ada":         "Ada",
+	"adb":         "Ada",
+	"ads":         "Ada",
+	"agda":        "Agda",
+	"alda":        "Alda",
+	"als":         "Alloy",
+	"ant":         "Ant",
+	"g4":          "ANTLR",
+	"apex":        "Apex",
+	"trigger":     "Apex",
+	"applescript": "AppleScript",
+	"scpt":        "AppleScript",
+	"adoc":        "AsciiDoc",
+	"asciidoc":    "AsciiDoc",
+	"asp":         "ASP",
+	"asa":         "ASP",
+	"aspx":        "ASP.NET",
+	"ascx":        "ASP.NET",
+	"asmx":        "ASP.NET",
+	"aj":          "AspectJ",
+	"asm":         "Assembly",
+	"s":           "Assembly",
+	"S":           "Assembly",
+	"astro":       "Astro",
+	"ats":         "ATS",
+	"dats":        "ATS",
+	"sats":        "ATS",
+	"hats":        "ATS",
+	"ahk":         "AutoHotkey",
+	"au3":         "AutoIt",
+	"avdl":        "Avro IDL",
+	"awk":         "Awk",
+	"bal":         "Ballerina",
+	"bat":         "Batch",
+	"btm":         "Batch",
+	"cmd":         "Batch",
+	"bash":        "BASH",
+	"bats":        "BASH",
+	"bib":         "BibTeX",
+	"bicep":       "Bicep",
+	"bb":          "BitBake",
+	"bbappend":    "BitBake",
+	"bbclass":     "BitBake",
+	"bsv":         "Bluespec",
+	"boo":         "Boo",
+	"sh":          "Bourne Shell",
+	"brs":         "BrightScript",
+	"c":           "C",
+	"h":           "C Header",
+	"csh":         "C Shell",
+	"tcsh":        "C Shell",
+	"cs":          "C#",
+	"cc":          "C++",
+	"cpp":         "C++",
+	"cxx":         "C++",
+	"c++":         "C++",
+	"pcc":         "C++",
+	"ino":         "C++",
+	"hh":          "C++ Header",
+	"hpp":         "C++ Header",
+	"hxx":         "C++ Header",
+	"inl":         "C++ Header",
+	"ipp":         "C++ Header",
+	"cairo":       "Cairo",
+	"capnp":       "Cap'n Proto",
+	"carbon":      "Carbon",
+	"ceylon":      "Ceylon",
+	"chpl":        "Chapel",
+	"circom":      "Circom",
+	"icl":         "Clean",
+	"dcl":         "Clean",
+	"clj":         "Clojure",
+	"cljc":        "Clojure",
+	"edn":         "Clojure",
+	"cljs":        "ClojureScript",
+	"cmake":       "CMake",
+	"cob":         "COBOL",
+	"cbl":         "COBOL",
+	"ccp":         "COBOL",
+	"cobol":       "COBOL",
+	"cpy":         "COBOL",
+	"ql":          "CodeQL",
+	"qll":         "CodeQL",
+	"coffee":      "CoffeeScript",
+	"litcoffee":   "CoffeeScript",
+	"cfm":         "ColdFusion",
+	"cfc":         "ColdFusion CFScript",
+	"cr":          "Crystal",
+	"css":         "CSS",
+	"csv":         "CSV",
+	"cu":          "CUDA",
+	"cuh":         "CUDA",
+	"cue":         "CUE",
+	"cypher":      "Cypher",
+	"cyp":         "Cypher",
+	"pyx":         "Cython",
+	"pxd":         "Cython",
+	"pxi":         "Cython",
+	"d":           "D",
+	"dart":        "Dart",
+	"dts":         "Devicetree",
+	"dtsi":        "Devicetree",
+	"dhall":       "Dhall",
+	"dockerfile":  "Dockerfile",
+	"dylan":       "Dylan",
+	"e":           "Eiffel",
+	"ejs":         "EJS",
+	"elv":         "Elvish",
+	"el":          "Emacs Lisp",
+	"elm":         "Elm",
+	"ex":          "Elixir",
+	"exs":         "Elixir",
+	"erb":         "ERB",
+	"erl":         "Erlang",
+	"hrl":         "Erlang",
+	"exp":         "Expect",
+	"fs":          "F#",
+	"fsi":         "F#",
+	"fsx":         "F#",
+	"fsscript":    "F#",
+	"fst":         "F*",
+	"fsti":        "F*",
+	"factor":      "Factor",
+	"fan":         "Fantom",
+	"fnl":         "Fennel",
+	"fish":        "Fish",
+	"fbs":         "FlatBuffers",
+	"4th":         "Forth",
+	"forth":       "Forth",
+	"fth":         "Forth",
+	"f":           "FORTRAN Legacy",
+	"F":           "FORTRAN Legacy",
+	"for":         "FORTRAN Legacy",
+	"ftn":         "FORTRAN Legacy",
+	"pfo":         "FORTRAN Legacy",
+	"f90":         "FORTRAN Modern",
+	"F90":         "FORTRAN Modern",
+	"f95":         "FORTRAN Modern",
+	"F95":         "FORTRAN Modern",
+	"f03":         "FORTRAN Modern",
+	"f08":         "FORTRAN Modern",
+	"ftl":         "FreeMarker",
+	"fut":         "Futhark",
+	"gd":          "GDScript",
+	"po":          "Gettext",
+	"pot":         "Gettext",
+	"feature":     "Gherkin",
+	"gleam":       "Gleam",
+	"glsl":        "GLSL",
+	"vert":        "GLSL",
+	"frag":        "GLSL",
+	"geom":        "GLSL",
+	"gn":          "GN",
+	"gni":         "GN",
+	"go":          "Go",
+	"gs":          "Gosu",
+	"gsx":         "Gosu",
+	"gradle":      "Gradle",
+	"gr":          "Grain",
+	"graphql":     "GraphQL",
+	"gql":         "GraphQL",
+	"groovy":      "Groovy",
+	"gvy":         "Groovy",
+	"hack":        "Hack",
+	"hhi":         "Hack",
+	"haml":        "Haml",
+	"hbs":         "Handlebars",
+	"handlebars":  "Handlebars",
+	"prg":         "Harbour",
+	"ha":          "Hare",
+	"hs":          "Haskell",
+	"lhs":         "Haskell",
+	"hx":          "Haxe",
+	"hcl":         "HCL",
+	"tf":          "HCL",
+	"tfvars":      "HCL",
+	"hlsl":        "HLSL",
+	"hc":          "HolyC",
+	"html":        "HTML",
+	"htm":         "HTML",
+	"xhtml":       "HTML",
+	"hy":          "Hy",
+	"idr":         "Idris",
+	"imba":        "Imba",
+	"ini":         "INI",
+	"iss":         "Inno Setup",
+	"io":          "Io",
+	"thy":         "Isabelle",
+	"jai":         "Jai",
+	"janet":       "Janet",
+	"java":        "Java",
+	"js":          "JavaScript",
+	"mjs":         "JavaScript",
+	"cjs":         "JavaScript",
+	"jinja":       "Jinja2",
+	"j2":          "Jinja2",
+	"json":        "JSON",
+	"json5":       "JSON5",
+	"jsonc":       "JSONC",
+	"jsonnet":     "Jsonnet",
+	"libsonnet":   "Jsonnet",
+	"jsp":         "JSP",
+	"jsx":         "JSX",
+	"jl":          "Julia",
+	"just":        "Just",
+	"kak":         "KakouneScript",
+	"kdl":         "KDL",
+	"kk":          "Koka",
+	"ksh":         "Korn Shell",
+	"kt":          "Kotlin",
+	"kts":         "Kotlin",
+	"ld":          "LD Script",
+	"lds":         "LD Script",
+	"lean":        "Lean",
+	"less":        "LESS",
+	"l":           "Lex",
+	"lex":         "Lex",
+	"lfe":         "LFE",
+	"ly":          "LilyPond",
+	"liquid":      "Liquid",
+	"lisp":        "Lisp",
+	"lsp":         "Lisp",
+	"cl":          "Lisp",
+	"ls":          "LiveScript",
+	"ll":          "LLVM",
+	"lgt":         "Logtalk",
+	"logtalk":     "Logtalk",
+	"lol":         "LOLCODE",
+	"lua":         "Lua",
+	"luau":        "Luau",
+	"m4":          "M4",
+	"makefile":    "Makefile",
+	"mk":          "Makefile",
+	"mak":         "Makefile",
+	"make":        "Makefile",
+	"mako":        "Mako",
+	"mao":         "Mako",
+	"md":          "Markdown",
+	"markdown":    "Markdown",
+	"maven":       "Maven",
+	"meson":       "Meson",
+	"mint":        "Mint",
+	"m3":          "Modula-3",
+	"i3":          "Modula-3",
+	"mg":          "Modula-3",
+	"ig":          "Modula-3",
+	"mojo":        "Mojo",
+	"moon":        "MoonScript",
+	"move":        "Move",
+	"mq4":         "MQL4",
+	"mqh":         "MQL4",
+	"mq5":         "MQL5",
+	"proj":        "MSBuild",
+	"props":       "MSBuild",
+	"targets":     "MSBuild",
+	"mustache":    "Mustache",
+	"nf":          "Nextflow",
+	"ncl":         "Nickel",
+	"nim":         "Nim",
+	"ninja":       "Ninja",
+	"nix":         "Nix",
+	"nsi":         "NSIS",
+	"nsh":         "NSIS",
+	"nu":          "Nu",
+	"njk":         "Nunjucks",
+	"m":           "Objective-C",
+	"mm":          "Objective-C++",
+	"ml":          "OCaml",
+	"mli":         "OCaml",
+	"odin":        "Odin",
+	"scad":        "OpenSCAD",
+	"org":         "Org",
+	"oz":          "Oz",
+	"p4":          "P4",
+	"pas":         "Pascal",
+	"dpr":         "Pascal",
+	"lpr":         "Pascal",
+	"pl":          "Perl",
+	"pm":          "Perl",
+	"perl":        "Perl",
+	"t":           "Perl",
+	"pest":        "Pest",
+	"php":         "PHP",
+	"php3":        "PHP",
+	"php4":        "PHP",
+	"php5":        "PHP",
+	"phtml":       "PHP",
+	"pike":        "Pike",
+	"pmod":        "Pike",
+	"pkl":         "Pkl",
+	"txt":         "Plain Text",
+	"plan9sh":     "Plan9 Shell",
+	"puml":        "PlantUML",
+	"plantuml":    "PlantUML",
+	"iuml":        "PlantUML",
+	"pony":        "Pony",
+	"pcss":        "PostCSS",
+	"postcss":     "PostCSS",
+	"ps1":         "PowerShell",
+	"psm1":        "PowerShell",
+	"psd1":        "PowerShell",
+	"prisma":      "Prisma",
+	"pde":         "Processing",
+	"prolog":      "Prolog",
+	"proto":       "Protocol Buffers",
+	"prql":        "PRQL",
+	"pug":         "Pug",
+	"jade":        "Pug",
+	"pp":          "Puppet",
+	"pb":          "PureBasic",
+	"pbi":         "PureBasic",
+	"purs":        "PureScript",
+	"py":          "Python",
+	"pyw":         "Python",
+	"pyi":         "Python",
+	"q":           "Q",
+	"pro":         "QMake",
+	"pri":         "QMake",
+	"qml":         "QML",
+	"r":           "R",
+	"R":           "R",
+	"rkt":         "Racket",
+	"rktl":        "Racket",
+	"raku":        "Raku",
+	"rakumod":     "Raku",
+	"p6":          "Raku",
+	"pm6":         "Raku",
+	"cshtml":      "Razor",
+	"re":          "Reason",
+	"rei":         "Reason",
+	"reb":         "Rebol",
+	"rebol":       "Rebol",
+	"r3":          "Rebol",
+	"red":         "Red",
+	"reds":        "Red",
+	"rego":        "Rego",
+	"res":         "ReScript",
+	"resi":        "ReScript",
+	"rst":         "ReStructuredText",
+	"rexx":        "Rexx",
+	"rex":         "Rexx",
+	"robot":       "Robot Framework",
+	"roc":         "Roc",
+	"ron":         "RON",
+	"spec":        "RPM Specfile",
+	"rb":          "Ruby",
+	"rake":        "Ruby",
+	"gemspec":     "Ruby",
+	"rs":          "Rust",
+	"sas":         "SAS",
+	"sass":        "Sass",
+	"scss":        "Sass",
+	"scala":       "Scala",
+	"sc":          "Scala",
+	"sbt":         "Scala",
+	"scm":         "Scheme",
+	"ss":          "Scheme",
+	"sld":         "Scheme",
+	"sci":         "Scilab",
+	"sce":         "Scilab",
+	"sed":         "sed",
+	"slim":        "Slim",
+	"st":          "Smalltalk",
+	"tpl":         "Smarty",
+	"smithy":      "Smithy",
+	"smk":         "Snakemake",
+	"sol":         "Solidity",
+	"sql":         "SQL",
+	"nut":         "Squirrel",
+	"stan":        "Stan",
+	"sml":         "Standard ML",
+	"sig":         "Standard ML",
+	"bzl":         "Starlark",
+	"star":        "Starlark",
+	"do":          "Stata",
+	"ado":         "Stata",
+	"styl":        "Stylus",
+	"svelte":      "Svelte",
+	"svg":         "SVG",
+	"swift":       "Swift",
+	"i":           "SWIG",
+	"swg":         "SWIG",
+	"sv":          "SystemVerilog",
+	"svh":         "SystemVerilog",
+	"tcl":         "Tcl/Tk",
+	"tk":          "Tcl/Tk",
+	"templ":       "Templ",
+	"tex":         "TeX",
+	"sty":         "TeX",
+	"cls":         "TeX",
+	"dtx":         "TeX",
+	"thrift":      "Thrift",
+	"tla":         "TLA+",
+	"toml":        "TOML",
+	"tsx":         "TSX",
+	"twig":        "Twig",
+	"ts":          "TypeScript",
+	"mts":         "TypeScript",
+	"cts":         "TypeScript",
+	"typ":         "Typst",
+	"uc":          "Unreal Script",
+	"ur":          "Ur/Web",
+	"urs":         "Ur/Web",
+	"vala":        "Vala",
+	"vm":          "Velocity",
+	"vtl":         "Velocity",
+	"v":           "Verilog",
+	"vhd":         "VHDL",
+	"vhdl":        "VHDL",
+	"vim":         "VimL",
+	"vb":          "Visual Basic",
+	"bas":         "Visual Basic",
+	"vbs":         "Visual Basic",
+	"vue":         "Vue",
+	"vy":          "Vyper",
+	"wat":         "WebAssembly",
+	"wast":        "WebAssembly",
+	"wgsl":        "WGSL",
+	"wxs":         "WiX",
+	"wxi":         "WiX",
+	"wxl":         "WiX",
+	"wl":          "Wolfram",
+	"wls":         "Wolfram",
+	"nb":          "Wolfram",
+	"xml":         "XML",
+	"xsd":         "XML",
+	"xsl":         "XML",
+	"xslt":        "XML",
+	"xaml":        "XML",
+	"plist":       "XML",
+	"csproj":      "XML",
+	"vbproj":      "XML",
+	"xq":          "XQuery",
+	"xquery":      "XQuery",
+	"xqm":         "XQuery",
+	"xtend":       "Xtend",
+	"y":           "Yacc",
+	"yy":          "Yacc",
+	"yaml":        "YAML",
+	"yml":         "YAML",
+	"yang":        "YANG",
+	"zig":         "Zig",
+	"zsh":         "Zsh",
 }
 
 var shebang2ext = map[string]string{
-	"gosh":    "scm",
-	"make":    "make",
-	"perl":    "pl",
-	"rc":      "plan9sh",
-	"python":  "py",
-	"ruby":    "rb",
-	"escript": "erl",
+	"gosh":     "scm",
+	"guile":    "scm",
+	"make":     "make",
+	"perl":     "pl",
+	"rc":       "plan9sh",
+	"python":   "py",
+	"pypy":     "py",
+	"ruby":     "rb",
+	"escript":  "erl",
+	"node":     "js",
+	"nodejs":   "js",
+	"deno":     "ts",
+	"php":      "php",
+	"lua":      "lua",
+	"Rscript":  "r",
+	"tclsh":    "tcl",
+	"wish":     "tcl",
+	"gawk":     "awk",
+	"mawk":     "awk",
+	"nawk":     "awk",
+	"dash":     "sh",
+	"crystal":  "cr",
+	"elixir":   "ex",
+	"julia":    "jl",
+	"groovy":   "groovy",
+	"pwsh":     "ps1",
+	"bats":     "bats",
+	"elvish":   "elv",
+	"expect":   "exp",
+	"hy":       "hy",
+	"janet":    "janet",
+	"moon":     "moon",
+	"nextflow": "nf",
+	"perl6":    "raku",
+	"pike":     "pike",
+	"raku":     "raku",
+	"rakudo":   "raku",
+	"sed":      "sed",
+}
+
+var filename2ext = map[string]string{
+	"BUILD.bazel":       "bzl",
+	"CMakeLists.txt":    "cmake",
+	"Containerfile":     "dockerfile",
+	"Dockerfile":        "dockerfile",
+	"GNUmakefile":       "makefile",
+	"Gemfile":           "rb",
+	"Guardfile":         "rb",
+	"Jenkinsfile":       "groovy",
+	"Makefile":          "makefile",
+	"Makefile.am":       "makefile",
+	"Nukefile":          "nu",
+	"Podfile":           "rb",
+	"Rakefile":          "rb",
+	"Snakefile":         "smk",
+	"Vagrantfile":       "rb",
+	"WORKSPACE":         "bzl",
+	"WORKSPACE.bazel":   "bzl",
+	"build.ninja":       "ninja",
+	"build.xml":         "ant",
+	"configure.ac":      "m4",
+	"justfile":          "just",
+	"meson.build":       "meson",
+	"meson_options.txt": "meson",
+	"pom.xml":           "maven",
+}
+
+// foldedFilenames are the file names matched regardless of case, by their lower case name.
+var foldedFilenames = map[string]string{
+	"containerfile": "Containerfile",
+	"dockerfile":    "Dockerfile",
+	"gnumakefile":   "GNUmakefile",
+	"justfile":      "justfile",
+	"makefile":      "Makefile",
+	"nukefile":      "Nukefile",
 }
 
-func getShebang(line string) (shebangLang string, ok bool) {
+// getShebang returns the interpreter of a shebang line.
+func getShebang(line string) (interpreter string, ok bool) {
 	ret := reShebangEnv.FindAllStringSubmatch(line, -1)
 	if ret != nil && len(ret[0]) == 3 {
-		shebangLang = ret[0][2]
-		if sl, ok := shebang2ext[shebangLang]; ok {
-			return sl, ok
-		}
-		return shebangLang, true
+		return ret[0][2], true
 	}
 
 	ret = reShebangLang.FindAllStringSubmatch(line, -1)
 	if ret != nil && len(ret[0]) >= 2 {
-		shebangLang = ret[0][1]
-		if sl, ok := shebang2ext[shebangLang]; ok {
-			return sl, ok
-		}
-		return shebangLang, true
+		return ret[0][1], true
 	}
 
 	return "", false
 }
 
-func getFileTypeByShebang(path string) (shebangLang string, ok bool) {
-	f, err := os.Open(path)
+func getFileTypeByShebang(fs afero.Fs, path string) (interpreter string, ok bool) {
+	f, err := fs.Open(path)
 	if err != nil {
 		return // ignore error
 	}
@@ -107,33 +818,205 @@ func getFileTypeByShebang(path string) (shebangLang string, ok bool) {
 	return
 }
 
-func getFileType(path string, _ *ClocOptions) (ext string, ok bool) {
-	ext = filepath.Ext(path)
+// Detection strategies of the file type, reported in the debug output.
+const (
+	strategyFilename  = "filename"
+	strategyShebang   = "shebang"
+	strategyExtension = "extension"
+)
 
-	shebangLang, ok := getFileTypeByShebang(path)
-	if ok {
-		return shebangLang, true
+// detectFileType returns the language name of path by its file name, shebang or extension,
+// and the strategy that decided it.
+func (langs *DefinedLanguages) detectFileType(path string, fs afero.Fs) (name, strategy string, ok bool) {
+	if name, ok := langs.filenameLanguage(filepath.Base(path)); ok {
+		return name, strategyFilename, true
 	}
 
-	if len(ext) >= 2 {
-		return ext[1:], true
+	if interpreter, ok := getFileTypeByShebang(fs, path); ok {
+		name, ok = langs.interpreterLanguage(interpreter)
+		return name, strategyShebang, ok
 	}
-	return ext, ok
+
+	if ext := filepath.Ext(path); len(ext) >= 2 {
+		name, ok = langs.ExtLanguage(ext[1:])
+		return name, strategyExtension, ok
+	}
+	return "", strategyExtension, false
+}
+
+// ambiguousExts are extensions shared by several languages. With ClocOptions.UseEnry,
+// files with these extensions are classified by enry.
+var ambiguousExts = map[string]struct{}{
+	"cl": {},
+	"fs": {},
+	"h":  {},
+	"m":  {},
+	"pl": {},
+	"pp": {},
+	"r":  {},
+	"ts": {},
+	"v":  {},
+}
+
+// enryLanguages maps the enry (linguist) language names that differ from DefinedLanguages.
+var enryLanguages = map[string]string{
+	"Ant Build System":   "Ant",
+	"Batchfile":          "Batch",
+	"Common Lisp":        "Lisp",
+	"Cuda":               "CUDA",
+	"Fortran":            "FORTRAN Legacy",
+	"Fortran Free Form":  "FORTRAN Modern",
+	"Gettext Catalog":    "Gettext",
+	"HTML+ERB":           "ERB",
+	"JSON with Comments": "JSONC",
+	"Less":               "LESS",
+	"Maven POM":          "Maven",
+	"Mathematica":        "Wolfram",
+	"PLSQL":              "SQL",
+	"Protocol Buffer":    "Protocol Buffers",
+	"RPM Spec":           "RPM Specfile",
+	"SCSS":               "Sass",
+	"Shell":              "Bourne Shell",
+	"TSQL":               "SQL",
+	"Tcl":                "Tcl/Tk",
+	"Text":               "Plain Text",
+	"Unix Assembly":      "Assembly",
+	"VBScript":           "Visual Basic",
+	"Vim Script":         "VimL",
+	"Visual Basic .NET":  "Visual Basic",
+	"fish":               "Fish",
+	"reStructuredText":   "ReStructuredText",
+}
+
+// fromEnryLanguage returns the name in langs for the enry language of path.
+func fromEnryLanguage(enryLang, path string, langs *DefinedLanguages) (string, bool) {
+	name := enryLang
+	if n, ok := enryLanguages[enryLang]; ok {
+		name = n
+	}
+
+	// headers are counted apart from the sources
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".h", ".hh", ".hpp", ".hxx":
+		switch name {
+		case "C":
+			name = "C Header"
+		case "C++":
+			name = "C++ Header"
+		}
+	}
+
+	if _, ok := langs.Langs[name]; ok {
+		return name, true
+	}
+	return "", false
+}
+
+// detectLanguage returns the name of the defined language of path and the strategy that decided it.
+func detectLanguage(path string, langs *DefinedLanguages, opts *ClocOptions) (name, strategy string, ok bool) {
+	name, strategy, ok = langs.detectFileType(path, opts.fs())
+
+	if opts.UseEnry {
+		_, ambiguous := ambiguousExts[strings.TrimPrefix(filepath.Ext(path), ".")]
+		if !ok || (ambiguous && strategy == strategyExtension) {
+			if n, s, found := detectByEnry(opts.fs(), path, langs); found {
+				return n, s, true
+			}
+		}
+	}
+	return name, strategy, ok
 }
 
 // NewLanguage create language data store.
+// Until WithStringLiterals sets the string literals of the language, the comment markers
+// between the common quotes on a line are code.
 func NewLanguage(name string, lineComments []string, multiLines [][]string) *Language {
 	return &Language{
-		Name:         name,
-		lineComments: lineComments,
-		multiLines:   multiLines,
-		Files:        []string{},
+		Name:            name,
+		lineComments:    lineComments,
+		multiLines:      multiLines,
+		stringLiterals:  guessStringLiterals(lineComments, multiLines),
+		guessedLiterals: true,
+		Files:           []string{},
 	}
 }
 
 // DefinedLanguages is the type information for mapping language name(key) and NewLanguage.
 type DefinedLanguages struct {
 	Langs map[string]*Language
+	// exts, filenames and interpreters map the extensions, the file names and the shebang
+	// interpreters to the language names. They are copied from the package level tables
+	// by Merge, until then the package level tables are used.
+	exts         map[string]string
+	filenames    map[string]string
+	interpreters map[string]string
+}
+
+// ExtLanguage returns the name of the language of the file extension ext, without the dot.
+func (langs *DefinedLanguages) ExtLanguage(ext string) (string, bool) {
+	exts := langs.exts
+	if exts == nil {
+		exts = Exts
+	}
+	name, ok := exts[ext]
+	return name, ok
+}
+
+// filenameLanguage returns the name of the language of the file name base.
+func (langs *DefinedLanguages) filenameLanguage(base string) (string, bool) {
+	lookup := func(filename string) (string, bool) {
+		if langs.filenames != nil {
+			name, ok := langs.filenames[filename]
+			return name, ok
+		}
+		if ext, ok := filename2ext[filename]; ok {
+			return langs.ExtLanguage(ext)
+		}
+		return "", false
+	}
+	if name, ok := lookup(base); ok {
+		return name, true
+	}
+	if filename, ok := foldedFilenames[strings.ToLower(base)]; ok {
+		return lookup(filename)
+	}
+	return "", false
+}
+
+// interpreterLanguage returns the name of the language of a shebang interpreter,
+// falling back to the interpreter as an extension, such as bash.
+func (langs *DefinedLanguages) interpreterLanguage(interpreter string) (string, bool) {
+	if langs.interpreters != nil {
+		if name, ok := langs.interpreters[interpreter]; ok {
+			return name, true
+		}
+	} else if ext, ok := shebang2ext[interpreter]; ok {
+		return langs.ExtLanguage(ext)
+	}
+	return langs.ExtLanguage(interpreter)
+}
+
+// ownLookupTables copies the package level tables into langs before they are changed.
+func (langs *DefinedLanguages) ownLookupTables() {
+	if langs.exts != nil {
+		return
+	}
+	langs.exts = make(map[string]string, len(Exts))
+	for ext, name := range Exts {
+		langs.exts[ext] = name
+	}
+	langs.filenames = make(map[string]string, len(filename2ext))
+	for filename, ext := range filename2ext {
+		if name, ok := Exts[ext]; ok {
+			langs.filenames[filename] = name
+		}
+	}
+	langs.interpreters = make(map[string]string, len(shebang2ext))
+	for interpreter, ext := range shebang2ext {
+		if name, ok := Exts[ext]; ok {
+			langs.interpreters[interpreter] = name
+		}
+	}
 }
 
 // GetFormattedString return DefinedLanguages as a human readable string.
@@ -144,14 +1027,309 @@ func (langs *DefinedLanguages) GetFormattedString() string {
 		printLangs = append(printLangs, lang.Name)
 	}
 	sort.Strings(printLangs)
+	for _, lang := range printLangs {
+		buf.WriteString(fmt.Sprintf("%-30v (%s)\n", lang, langs.lang2exts(lang)))
+	}
 	return buf.String()
 }
 
+func (langs *DefinedLanguages) lang2exts(lang string) (exts string) {
+	table := langs.exts
+	if table == nil {
+		table = Exts
+	}
+	var es []string
+	for ext, l := range table {
+		if lang == l {
+			es = append(es, ext)
+		}
+	}
+	sort.Strings(es)
+	return strings.Join(es, ", ")
+}
+
 // NewDefinedLanguages create DefinedLanguages.
 func NewDefinedLanguages() *DefinedLanguages {
 	return &DefinedLanguages{
 		Langs: map[string]*Language{
-			"Go": NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"ABAP":                NewLanguage("ABAP", []string{"*", `"`}, [][]string{{"", ""}}),
+			"ABNF":                NewLanguage("ABNF", []string{";"}, [][]string{{"", ""}}),
+			"ActionScript":        NewLanguage("ActionScript", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Ada":                 NewLanguage("Ada", []string{"--"}, [][]string{{"", ""}}),
+			"Agda":                NewLanguage("Agda", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Alda":                NewLanguage("Alda", []string{"#"}, [][]string{{"", ""}}),
+			"Alloy":               NewLanguage("Alloy", []string{"//", "--"}, [][]string{{"/*", "*/"}}),
+			"Ant":                 NewLanguage("Ant", []string{}, [][]string{{"<!--", "-->"}}),
+			"ANTLR":               NewLanguage("ANTLR", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Apex":                NewLanguage("Apex", []string{"//"}, [][]string{{"/*", "*/"}}).WithDocComments(javadocComments),
+			"AppleScript":         NewLanguage("AppleScript", []string{"--", "#"}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"AsciiDoc":            NewLanguage("AsciiDoc", []string{"//"}, [][]string{{"////", "////"}}),
+			"ASP":                 NewLanguage("ASP", []string{"'", "REM"}, [][]string{{"<!--", "-->"}}),
+			"ASP.NET":             NewLanguage("ASP.NET", []string{}, [][]string{{"<!--", "-->"}, {"<%--", "--%>"}}),
+			"AspectJ":             NewLanguage("AspectJ", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jvmStringLiterals...).WithDocComments(javadocComments),
+			"Assembly":            NewLanguage("Assembly", []string{"//", ";", "#", "@", "|", "!"}, [][]string{{"/*", "*/"}}),
+			"Astro":               NewLanguage("Astro", []string{"//"}, [][]string{{"/*", "*/"}, {"<!--", "-->"}}),
+			"ATS":                 NewLanguage("ATS", []string{"//"}, [][]string{{"(*", "*)"}, {"/*", "*/"}}),
+			"AutoHotkey":          NewLanguage("AutoHotkey", []string{";"}, [][]string{{"/*", "*/"}}),
+			"AutoIt":              NewLanguage("AutoIt", []string{";"}, [][]string{{"#cs", "#ce"}}),
+			"Avro IDL":            NewLanguage("Avro IDL", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Awk":                 NewLanguage("Awk", []string{"#"}, [][]string{{"", ""}}),
+			"Ballerina":           NewLanguage("Ballerina", []string{"//"}, [][]string{{"", ""}}),
+			"Batch":               NewLanguage("Batch", []string{"REM", "rem", "::"}, [][]string{{"", ""}}),
+			"BASH":                NewLanguage("BASH", []string{"#"}, [][]string{{"", ""}}),
+			"BibTeX":              NewLanguage("BibTeX", []string{"%"}, [][]string{{"", ""}}),
+			"Bicep":               NewLanguage("Bicep", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"BitBake":             NewLanguage("BitBake", []string{"#"}, [][]string{{"", ""}}),
+			"Bluespec":            NewLanguage("Bluespec", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Boo":                 NewLanguage("Boo", []string{"#", "//"}, [][]string{{"/*", "*/"}}),
+			"Bourne Shell":        NewLanguage("Bourne Shell", []string{"#"}, [][]string{{"", ""}}),
+			"BrightScript":        NewLanguage("BrightScript", []string{"'"}, [][]string{{"", ""}}),
+			"C":                   NewLanguage("C", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"C Header":            NewLanguage("C Header", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"C Shell":             NewLanguage("C Shell", []string{"#"}, [][]string{{"", ""}}),
+			"C#":                  NewLanguage("C#", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(tripleSlashDocs),
+			"C++":                 NewLanguage("C++", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"C++ Header":          NewLanguage("C++ Header", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"Cairo":               NewLanguage("Cairo", []string{"//"}, [][]string{{"", ""}}),
+			"Cap'n Proto":         NewLanguage("Cap'n Proto", []string{"#"}, [][]string{{"", ""}}),
+			"Carbon":              NewLanguage("Carbon", []string{"//"}, [][]string{{"", ""}}),
+			"Ceylon":              NewLanguage("Ceylon", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Chapel":              NewLanguage("Chapel", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Circom":              NewLanguage("Circom", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Clean":               NewLanguage("Clean", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"Clojure":             NewLanguage("Clojure", []string{";", "#_"}, [][]string{{"", ""}}),
+			"ClojureScript":       NewLanguage("ClojureScript", []string{";", "#_"}, [][]string{{"", ""}}),
+			"CMake":               NewLanguage("CMake", []string{"#"}, [][]string{{"#[[", "]]"}}),
+			"COBOL":               NewLanguage("COBOL", []string{"*", "/"}, [][]string{{"", ""}}).withLineStartComments(),
+			"CodeQL":              NewLanguage("CodeQL", []string{"//"}, [][]string{{"/*", "*/"}}).WithDocComments(javadocComments),
+			"CoffeeScript":        NewLanguage("CoffeeScript", []string{"#"}, [][]string{{"###", "###"}}),
+			"ColdFusion":          NewLanguage("ColdFusion", []string{}, [][]string{{"<!---", "--->"}}),
+			"ColdFusion CFScript": NewLanguage("ColdFusion CFScript", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Crystal":             NewLanguage("Crystal", []string{"#"}, [][]string{{"", ""}}),
+			"CSS":                 NewLanguage("CSS", []string{}, [][]string{{"/*", "*/"}}),
+			"CSV":                 NewLanguage("CSV", []string{}, [][]string{{"", ""}}),
+			"CUDA":                NewLanguage("CUDA", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...),
+			"CUE":                 NewLanguage("CUE", []string{"//"}, [][]string{{"", ""}}),
+			"Cypher":              NewLanguage("Cypher", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Cython":              NewLanguage("Cython", []string{"#"}, [][]string{{"\"\"\"", "\"\"\""}}),
+			"D":                   NewLanguage("D", []string{"//"}, [][]string{{"/*", "*/"}, {"/+", "+/"}}).WithNestedComments("/+").WithStringLiterals(cStringLiterals...).WithDocComments(DocComments{LineComments: []string{"///"}, MultiLines: []string{"/**", "/++"}}),
+			"Dart":                NewLanguage("Dart", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*").WithStringLiterals(pythonStringLiterals...).WithDocComments(tripleSlashDocs),
+			"Devicetree":          NewLanguage("Devicetree", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Dhall":               NewLanguage("Dhall", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Dockerfile":          NewLanguage("Dockerfile", []string{"#"}, [][]string{{"", ""}}),
+			"Dylan":               NewLanguage("Dylan", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Eiffel":              NewLanguage("Eiffel", []string{"--"}, [][]string{{"", ""}}),
+			"EJS":                 NewLanguage("EJS", []string{}, [][]string{{"<%#", "%>"}, {"<!--", "-->"}}),
+			"Elixir":              NewLanguage("Elixir", []string{"#"}, [][]string{{"", ""}}),
+			"Elm":                 NewLanguage("Elm", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Elvish":              NewLanguage("Elvish", []string{"#"}, [][]string{{"", ""}}),
+			"Emacs Lisp":          NewLanguage("Emacs Lisp", []string{";"}, [][]string{{"", ""}}),
+			"ERB":                 NewLanguage("ERB", []string{}, [][]string{{"<%#", "%>"}}),
+			"Erlang":              NewLanguage("Erlang", []string{"%"}, [][]string{{"", ""}}),
+			"Expect":              NewLanguage("Expect", []string{"#"}, [][]string{{"", ""}}),
+			"F#":                  NewLanguage("F#", []string{"//"}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"F*":                  NewLanguage("F*", []string{"//"}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Factor":              NewLanguage("Factor", []string{"!", "#!"}, [][]string{{"", ""}}),
+			"Fantom":              NewLanguage("Fantom", []string{"//", "**"}, [][]string{{"/*", "*/"}}),
+			"Fennel":              NewLanguage("Fennel", []string{";"}, [][]string{{"", ""}}),
+			"Fish":                NewLanguage("Fish", []string{"#"}, [][]string{{"", ""}}),
+			"FlatBuffers":         NewLanguage("FlatBuffers", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Forth":               NewLanguage("Forth", []string{"\\"}, [][]string{{"(", ")"}}),
+			"FORTRAN Legacy":      NewLanguage("FORTRAN Legacy", []string{"c", "C", "!", "*"}, [][]string{{"", ""}}).withLineStartComments(),
+			"FORTRAN Modern":      NewLanguage("FORTRAN Modern", []string{"!"}, [][]string{{"", ""}}),
+			"FreeMarker":          NewLanguage("FreeMarker", []string{}, [][]string{{"<#--", "-->"}}),
+			"Futhark":             NewLanguage("Futhark", []string{"--"}, [][]string{{"", ""}}),
+			"GDScript":            NewLanguage("GDScript", []string{"#"}, [][]string{{"", ""}}),
+			"Gettext":             NewLanguage("Gettext", []string{"#"}, [][]string{{"", ""}}),
+			"Gherkin":             NewLanguage("Gherkin", []string{"#"}, [][]string{{"", ""}}),
+			"Gleam":               NewLanguage("Gleam", []string{"//"}, [][]string{{"", ""}}),
+			"GLSL":                NewLanguage("GLSL", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"GN":                  NewLanguage("GN", []string{"#"}, [][]string{{"", ""}}),
+			"Go":                  NewLanguage("Go", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(goStringLiterals...).WithDocComments(DocComments{Declaration: goExportedDeclaration}),
+			"Gosu":                NewLanguage("Gosu", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Gradle":              NewLanguage("Gradle", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Grain":               NewLanguage("Grain", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"GraphQL":             NewLanguage("GraphQL", []string{"#"}, [][]string{{"", ""}}),
+			"Groovy":              NewLanguage("Groovy", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jvmStringLiterals...).WithDocComments(javadocComments),
+			"Hack":                NewLanguage("Hack", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"Haml":                NewLanguage("Haml", []string{"-#"}, [][]string{{"", ""}}),
+			"Handlebars":          NewLanguage("Handlebars", []string{}, [][]string{{"<!--", "-->"}, {"{{!", "}}"}}),
+			"Harbour":             NewLanguage("Harbour", []string{"//", "&&"}, [][]string{{"/*", "*/"}}),
+			"Hare":                NewLanguage("Hare", []string{"//"}, [][]string{{"", ""}}),
+			"Haskell":             NewLanguage("Haskell", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Haxe":                NewLanguage("Haxe", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"HCL":                 NewLanguage("HCL", []string{"#", "//"}, [][]string{{"/*", "*/"}}),
+			"HLSL":                NewLanguage("HLSL", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"HolyC":               NewLanguage("HolyC", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"HTML":                NewLanguage("HTML", []string{}, [][]string{{"<!--", "-->"}}),
+			"Hy":                  NewLanguage("Hy", []string{";"}, [][]string{{"", ""}}),
+			"Idris":               NewLanguage("Idris", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Imba":                NewLanguage("Imba", []string{"#"}, [][]string{{"###", "###"}}),
+			"INI":                 NewLanguage("INI", []string{"#", ";"}, [][]string{{"", ""}}),
+			"Inno Setup":          NewLanguage("Inno Setup", []string{";", "//"}, [][]string{{"{", "}"}}),
+			"Io":                  NewLanguage("Io", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"Isabelle":            NewLanguage("Isabelle", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Jai":                 NewLanguage("Jai", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"Janet":               NewLanguage("Janet", []string{"#"}, [][]string{{"", ""}}),
+			"Java":                NewLanguage("Java", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jvmStringLiterals...).WithDocComments(javadocComments),
+			"JavaScript":          NewLanguage("JavaScript", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jsStringLiterals...).WithDocComments(javadocComments),
+			"Jinja2":              NewLanguage("Jinja2", []string{}, [][]string{{"{#", "#}"}, {"<!--", "-->"}}),
+			"JSON":                NewLanguage("JSON", []string{}, [][]string{{"", ""}}),
+			"JSON5":               NewLanguage("JSON5", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"JSONC":               NewLanguage("JSONC", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Jsonnet":             NewLanguage("Jsonnet", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"JSP":                 NewLanguage("JSP", []string{"//"}, [][]string{{"/*", "*/"}, {"<%--", "--%>"}}),
+			"JSX":                 NewLanguage("JSX", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jsStringLiterals...).WithDocComments(javadocComments),
+			"Julia":               NewLanguage("Julia", []string{"#"}, [][]string{{"#=", "=#"}}).WithNestedComments("#="),
+			"Just":                NewLanguage("Just", []string{"#"}, [][]string{{"", ""}}),
+			"KakouneScript":       NewLanguage("KakouneScript", []string{"#"}, [][]string{{"", ""}}),
+			"KDL":                 NewLanguage("KDL", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"Koka":                NewLanguage("Koka", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"Korn Shell":          NewLanguage("Korn Shell", []string{"#"}, [][]string{{"", ""}}),
+			"Kotlin":              NewLanguage("Kotlin", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*").WithStringLiterals(jvmStringLiterals...).WithDocComments(javadocComments),
+			"LD Script":           NewLanguage("LD Script", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Lean":                NewLanguage("Lean", []string{"--"}, [][]string{{"/-", "-/"}}).WithNestedComments("/-"),
+			"LESS":                NewLanguage("LESS", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Lex":                 NewLanguage("Lex", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"LFE":                 NewLanguage("LFE", []string{";"}, [][]string{{"#|", "|#"}}),
+			"LilyPond":            NewLanguage("LilyPond", []string{"%"}, [][]string{{"%{", "%}"}}),
+			"Liquid":              NewLanguage("Liquid", []string{}, [][]string{{"{% comment %}", "{% endcomment %}"}, {"<!--", "-->"}}),
+			"Lisp":                NewLanguage("Lisp", []string{";"}, [][]string{{"#|", "|#"}}).WithNestedComments("#|"),
+			"LiveScript":          NewLanguage("LiveScript", []string{"#"}, [][]string{{"/*", "*/"}}),
+			"LLVM":                NewLanguage("LLVM", []string{";"}, [][]string{{"", ""}}),
+			"Logtalk":             NewLanguage("Logtalk", []string{"%"}, [][]string{{"/*", "*/"}}),
+			"LOLCODE":             NewLanguage("LOLCODE", []string{"BTW"}, [][]string{{"OBTW", "TLDR"}}),
+			"Lua":                 NewLanguage("Lua", []string{"--"}, [][]string{{"--[[", "]]"}}),
+			"Luau":                NewLanguage("Luau", []string{"--"}, [][]string{{"--[[", "]]"}}),
+			"M4":                  NewLanguage("M4", []string{"#", "dnl"}, [][]string{{"", ""}}),
+			"Makefile":            NewLanguage("Makefile", []string{"#"}, [][]string{{"", ""}}),
+			"Mako":                NewLanguage("Mako", []string{"##"}, [][]string{{"<%doc>", "</%doc>"}}),
+			"Markdown":            NewLanguage("Markdown", []string{}, [][]string{{"", ""}}),
+			"Maven":               NewLanguage("Maven", []string{}, [][]string{{"<!--", "-->"}}),
+			"Meson":               NewLanguage("Meson", []string{"#"}, [][]string{{"", ""}}),
+			"Mint":                NewLanguage("Mint", []string{"//"}, [][]string{{"", ""}}),
+			"Modula-3":            NewLanguage("Modula-3", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Mojo":                NewLanguage("Mojo", []string{"#"}, [][]string{{`"""`, `"""`}}).WithStringLiterals(pythonStringLiterals...).WithDocComments(DocComments{MultiLines: []string{`"""`}}),
+			"MoonScript":          NewLanguage("MoonScript", []string{"--"}, [][]string{{"", ""}}),
+			"Move":                NewLanguage("Move", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"MQL4":                NewLanguage("MQL4", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"MQL5":                NewLanguage("MQL5", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"MSBuild":             NewLanguage("MSBuild", []string{}, [][]string{{"<!--", "-->"}}),
+			"Mustache":            NewLanguage("Mustache", []string{}, [][]string{{"{{!", "}}"}}),
+			"Nextflow":            NewLanguage("Nextflow", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Nickel":              NewLanguage("Nickel", []string{"#"}, [][]string{{"", ""}}),
+			"Nim":                 NewLanguage("Nim", []string{"#"}, [][]string{{"#[", "]#"}}).WithNestedComments("#["),
+			"Ninja":               NewLanguage("Ninja", []string{"#"}, [][]string{{"", ""}}),
+			"Nix":                 NewLanguage("Nix", []string{"#"}, [][]string{{"/*", "*/"}}),
+			"NSIS":                NewLanguage("NSIS", []string{"#", ";"}, [][]string{{"/*", "*/"}}),
+			"Nu":                  NewLanguage("Nu", []string{";", "#"}, [][]string{{"", ""}}),
+			"Nunjucks":            NewLanguage("Nunjucks", []string{}, [][]string{{"{#", "#}"}, {"<!--", "-->"}}),
+			"Objective-C":         NewLanguage("Objective-C", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"Objective-C++":       NewLanguage("Objective-C++", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(doxygenComments),
+			"OCaml":               NewLanguage("OCaml", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Odin":                NewLanguage("Odin", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"OpenSCAD":            NewLanguage("OpenSCAD", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Org":                 NewLanguage("Org", []string{"#"}, [][]string{{"", ""}}),
+			"Oz":                  NewLanguage("Oz", []string{"%"}, [][]string{{"/*", "*/"}}),
+			"P4":                  NewLanguage("P4", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Pascal":              NewLanguage("Pascal", []string{"//"}, [][]string{{"{", "}"}, {"(*", "*)"}}),
+			"Perl":                NewLanguage("Perl", []string{"#"}, [][]string{{"=pod", "=cut"}}),
+			"Pest":                NewLanguage("Pest", []string{"//"}, [][]string{{"", ""}}),
+			"PHP":                 NewLanguage("PHP", []string{"#", "//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(cStringLiterals...).WithDocComments(javadocComments),
+			"Pike":                NewLanguage("Pike", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Pkl":                 NewLanguage("Pkl", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Plain Text":          NewLanguage("Plain Text", []string{}, [][]string{{"", ""}}),
+			"Plan9 Shell":         NewLanguage("Plan9 Shell", []string{"#"}, [][]string{{"", ""}}),
+			"PlantUML":            NewLanguage("PlantUML", []string{"'"}, [][]string{{"/'", "'/"}}),
+			"Pony":                NewLanguage("Pony", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"PostCSS":             NewLanguage("PostCSS", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"PowerShell":          NewLanguage("PowerShell", []string{"#"}, [][]string{{"<#", "#>"}}),
+			"Prisma":              NewLanguage("Prisma", []string{"//"}, [][]string{{"", ""}}),
+			"Processing":          NewLanguage("Processing", []string{"//"}, [][]string{{"/*", "*/"}}).WithDocComments(javadocComments),
+			"Prolog":              NewLanguage("Prolog", []string{"%"}, [][]string{{"/*", "*/"}}),
+			"Protocol Buffers":    NewLanguage("Protocol Buffers", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"PRQL":                NewLanguage("PRQL", []string{"#"}, [][]string{{"", ""}}),
+			"Pug":                 NewLanguage("Pug", []string{"//"}, [][]string{{"", ""}}),
+			"Puppet":              NewLanguage("Puppet", []string{"#"}, [][]string{{"", ""}}),
+			"PureBasic":           NewLanguage("PureBasic", []string{";"}, [][]string{{"", ""}}),
+			"PureScript":          NewLanguage("PureScript", []string{"--"}, [][]string{{"{-", "-}"}}).WithNestedComments("{-"),
+			"Python":              NewLanguage("Python", []string{"#"}, [][]string{{"\"\"\"", "\"\"\""}}).WithStringLiterals(pythonStringLiterals...).WithDocComments(DocComments{MultiLines: []string{`"""`}}),
+			"Q":                   NewLanguage("Q", []string{"/"}, [][]string{{"", ""}}),
+			"QMake":               NewLanguage("QMake", []string{"#"}, [][]string{{"", ""}}),
+			"QML":                 NewLanguage("QML", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"R":                   NewLanguage("R", []string{"#"}, [][]string{{"", ""}}),
+			"Racket":              NewLanguage("Racket", []string{";"}, [][]string{{"#|", "|#"}}).WithNestedComments("#|"),
+			"Raku":                NewLanguage("Raku", []string{"#"}, [][]string{{"=begin", "=end"}}),
+			"Razor":               NewLanguage("Razor", []string{}, [][]string{{"<!--", "-->"}, {"@*", "*@"}}),
+			"Reason":              NewLanguage("Reason", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Rebol":               NewLanguage("Rebol", []string{";"}, [][]string{{"", ""}}),
+			"Red":                 NewLanguage("Red", []string{";"}, [][]string{{"", ""}}),
+			"Rego":                NewLanguage("Rego", []string{"#"}, [][]string{{"", ""}}),
+			"ReScript":            NewLanguage("ReScript", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"ReStructuredText":    NewLanguage("ReStructuredText", []string{}, [][]string{{"", ""}}),
+			"Rexx":                NewLanguage("Rexx", []string{}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"Robot Framework":     NewLanguage("Robot Framework", []string{"#"}, [][]string{{"", ""}}),
+			"Roc":                 NewLanguage("Roc", []string{"#"}, [][]string{{"", ""}}),
+			"RON":                 NewLanguage("RON", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*"),
+			"RPM Specfile":        NewLanguage("RPM Specfile", []string{"#"}, [][]string{{"", ""}}),
+			"Ruby":                NewLanguage("Ruby", []string{"#"}, [][]string{{"=begin", "=end"}}).WithStringLiterals(cStringLiterals...),
+			"Rust":                NewLanguage("Rust", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*").WithStringLiterals(rustStringLiterals...).WithDocComments(doxygenComments),
+			"SAS":                 NewLanguage("SAS", []string{"*"}, [][]string{{"/*", "*/"}}),
+			"Sass":                NewLanguage("Sass", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Scala":               NewLanguage("Scala", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*").WithStringLiterals(jvmStringLiterals...).WithDocComments(javadocComments),
+			"Scheme":              NewLanguage("Scheme", []string{";"}, [][]string{{"#|", "|#"}}).WithNestedComments("#|"),
+			"Scilab":              NewLanguage("Scilab", []string{"//"}, [][]string{{"", ""}}),
+			"sed":                 NewLanguage("sed", []string{"#"}, [][]string{{"", ""}}),
+			"Slim":                NewLanguage("Slim", []string{"/"}, [][]string{{"", ""}}),
+			"Smalltalk":           NewLanguage("Smalltalk", []string{}, [][]string{{`"`, `"`}}),
+			"Smarty":              NewLanguage("Smarty", []string{}, [][]string{{"{*", "*}"}}),
+			"Smithy":              NewLanguage("Smithy", []string{"//"}, [][]string{{"", ""}}),
+			"Snakemake":           NewLanguage("Snakemake", []string{"#"}, [][]string{{`"""`, `"""`}}).WithStringLiterals(pythonStringLiterals...),
+			"Solidity":            NewLanguage("Solidity", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"SQL":                 NewLanguage("SQL", []string{"--"}, [][]string{{"/*", "*/"}}),
+			"Squirrel":            NewLanguage("Squirrel", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"Stan":                NewLanguage("Stan", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"Standard ML":         NewLanguage("Standard ML", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Starlark":            NewLanguage("Starlark", []string{"#"}, [][]string{{"", ""}}),
+			"Stata":               NewLanguage("Stata", []string{"//", "*"}, [][]string{{"/*", "*/"}}),
+			"Stylus":              NewLanguage("Stylus", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Svelte":              NewLanguage("Svelte", []string{"//"}, [][]string{{"/*", "*/"}, {"<!--", "-->"}}),
+			"SVG":                 NewLanguage("SVG", []string{}, [][]string{{"<!--", "-->"}}),
+			"Swift":               NewLanguage("Swift", []string{"//"}, [][]string{{"/*", "*/"}}).WithNestedComments("/*").WithStringLiterals(jvmStringLiterals...).WithDocComments(tripleSlashDocs),
+			"SWIG":                NewLanguage("SWIG", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"SystemVerilog":       NewLanguage("SystemVerilog", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Tcl/Tk":              NewLanguage("Tcl/Tk", []string{"#"}, [][]string{{"", ""}}),
+			"Templ":               NewLanguage("Templ", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"TeX":                 NewLanguage("TeX", []string{"%"}, [][]string{{"", ""}}),
+			"Thrift":              NewLanguage("Thrift", []string{"//", "#"}, [][]string{{"/*", "*/"}}),
+			"TLA+":                NewLanguage("TLA+", []string{"\\*"}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"TOML":                NewLanguage("TOML", []string{"#"}, [][]string{{"", ""}}),
+			"TSX":                 NewLanguage("TSX", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jsStringLiterals...).WithDocComments(javadocComments),
+			"Twig":                NewLanguage("Twig", []string{}, [][]string{{"{#", "#}"}, {"<!--", "-->"}}),
+			"TypeScript":          NewLanguage("TypeScript", []string{"//"}, [][]string{{"/*", "*/"}}).WithStringLiterals(jsStringLiterals...).WithDocComments(javadocComments),
+			"Typst":               NewLanguage("Typst", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Unreal Script":       NewLanguage("Unreal Script", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Ur/Web":              NewLanguage("Ur/Web", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"Vala":                NewLanguage("Vala", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Velocity":            NewLanguage("Velocity", []string{"##"}, [][]string{{"#*", "*#"}}),
+			"Verilog":             NewLanguage("Verilog", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"VHDL":                NewLanguage("VHDL", []string{"--"}, [][]string{{"", ""}}),
+			"VimL":                NewLanguage("VimL", []string{`"`}, [][]string{{"", ""}}),
+			"Visual Basic":        NewLanguage("Visual Basic", []string{"'"}, [][]string{{"", ""}}),
+			"Vue":                 NewLanguage("Vue", []string{"//"}, [][]string{{"/*", "*/"}, {"<!--", "-->"}}),
+			"Vyper":               NewLanguage("Vyper", []string{"#"}, [][]string{{`"""`, `"""`}}).WithStringLiterals(pythonStringLiterals...),
+			"WebAssembly":         NewLanguage("WebAssembly", []string{";;"}, [][]string{{"(;", ";)"}}),
+			"WGSL":                NewLanguage("WGSL", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"WiX":                 NewLanguage("WiX", []string{}, [][]string{{"<!--", "-->"}}),
+			"Wolfram":             NewLanguage("Wolfram", []string{}, [][]string{{"(*", "*)"}}).WithNestedComments("(*"),
+			"XML":                 NewLanguage("XML", []string{}, [][]string{{"<!--", "-->"}}),
+			"XQuery":              NewLanguage("XQuery", []string{}, [][]string{{"(:", ":)"}}).WithNestedComments("(:"),
+			"Xtend":               NewLanguage("Xtend", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Yacc":                NewLanguage("Yacc", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"YAML":                NewLanguage("YAML", []string{"#"}, [][]string{{"", ""}}),
+			"YANG":                NewLanguage("YANG", []string{"//"}, [][]string{{"/*", "*/"}}),
+			"Zig":                 NewLanguage("Zig", []string{"//"}, [][]string{{"", ""}}),
+			"Zsh":                 NewLanguage("Zsh", []string{"#"}, [][]string{{"", ""}}),
 		},
 	}
 }
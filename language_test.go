@@ -1,15 +1,20 @@
 package gocloc
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+)
 
 func TestGetShebang(t *testing.T) {
-	lang := "py"
+	lang := "Python"
 	shebang := "#!/usr/bin/env python"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -17,13 +22,14 @@ func TestGetShebang(t *testing.T) {
 }
 
 func TestGetShebangWithSpace(t *testing.T) {
-	lang := "py"
+	lang := "Python"
 	shebang := "#! /usr/bin/env python"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -31,13 +37,14 @@ func TestGetShebangWithSpace(t *testing.T) {
 }
 
 func TestGetShebangBashWithEnv(t *testing.T) {
-	lang := "bash"
+	lang := "BASH"
 	shebang := "#!/usr/bin/env bash"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -45,13 +52,14 @@ func TestGetShebangBashWithEnv(t *testing.T) {
 }
 
 func TestGetShebangBash(t *testing.T) {
-	lang := "bash"
+	lang := "BASH"
 	shebang := "#!/usr/bin/bash"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -59,13 +67,14 @@ func TestGetShebangBash(t *testing.T) {
 }
 
 func TestGetShebangBashWithSpace(t *testing.T) {
-	lang := "bash"
+	lang := "BASH"
 	shebang := "#! /usr/bin/bash"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -73,13 +82,14 @@ func TestGetShebangBashWithSpace(t *testing.T) {
 }
 
 func TestGetShebangPlan9Shell(t *testing.T) {
-	lang := "plan9sh"
+	lang := "Plan9 Shell"
 	shebang := "#!/usr/rc"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
@@ -87,15 +97,191 @@ func TestGetShebangPlan9Shell(t *testing.T) {
 }
 
 func TestGetShebangStartDot(t *testing.T) {
-	lang := "pl"
+	lang := "Perl"
 	shebang := "#!./perl -o"
 
 	s, ok := getShebang(shebang)
 	if !ok {
 		t.Errorf("invalid logic. shebang=[%v]", shebang)
 	}
+	s, _ = NewDefinedLanguages().interpreterLanguage(s)
 
 	if lang != s {
 		t.Errorf("invalid logic. lang=[%v] shebang=[%v]", lang, s)
 	}
 }
+
+func TestDefinedLanguagesCoverExts(t *testing.T) {
+	langs := NewDefinedLanguages()
+	for ext, name := range Exts {
+		if _, ok := langs.Langs[name]; !ok {
+			t.Errorf("invalid definition. ext=[%v] lang=[%v]", ext, name)
+		}
+	}
+	for name, lang := range langs.Langs {
+		if name != lang.Name {
+			t.Errorf("invalid definition. key=[%v] name=[%v]", name, lang.Name)
+		}
+	}
+}
+
+func TestShebang2ExtCoverExts(t *testing.T) {
+	for interp, ext := range shebang2ext {
+		if _, ok := Exts[ext]; !ok {
+			t.Errorf("invalid definition. interpreter=[%v] ext=[%v]", interp, ext)
+		}
+	}
+}
+
+func TestGetFileTypeByFilename(t *testing.T) {
+	tests := map[string]string{
+		"Makefile":           "Makefile",
+		"src/GNUmakefile":    "Makefile",
+		"Makefile.am":        "Makefile",
+		"Dockerfile":         "Dockerfile",
+		"CMakeLists.txt":     "CMake",
+		"meson.build":        "Meson",
+		"Gemfile":            "Ruby",
+		"pom.xml":            "Maven",
+		"main.go":            "Go",
+		"app/index.ts":       "TypeScript",
+		"script.py":          "Python",
+		"deploy/values.yaml": "YAML",
+		"makefile":           "Makefile",
+		"src/DOCKERFILE":     "Dockerfile",
+		"containerfile":      "Dockerfile",
+		"Justfile":           "Just",
+		"workflow/Snakefile": "Snakemake",
+		"t/basic.t":          "Perl",
+		"Main.lhs":           "Haskell",
+		"refs.bib":           "BibTeX",
+		"thesis.cls":         "TeX",
+		"config.json5":       "JSON5",
+		"tsconfig.jsonc":     "JSONC",
+		"test.bats":          "BASH",
+		"hello.hy":           "Hy",
+		"README.litcoffee":   "CoffeeScript",
+		"index.slim":         "Slim",
+		"index.ejs":          "EJS",
+		"page.liquid":        "Liquid",
+	}
+	langs := NewDefinedLanguages()
+	fs := afero.NewMemMapFs()
+	for path, expected := range tests {
+		lang, _, ok := langs.detectFileType(path, fs)
+		if !ok {
+			t.Errorf("invalid logic. path=[%v]", path)
+			continue
+		}
+		if lang != expected {
+			t.Errorf("invalid logic. path=[%v] lang=[%v] expected=[%v]", path, lang, expected)
+		}
+	}
+}
+
+func TestFromEnryLanguage(t *testing.T) {
+	langs := NewDefinedLanguages()
+	tests := []struct {
+		enryLang string
+		path     string
+		name     string
+		ok       bool
+	}{
+		{"Go", "main.go", "Go", true},
+		{"Shell", "script", "Bourne Shell", true},
+		{"Common Lisp", "a.cl", "Lisp", true},
+		{"C", "a.c", "C", true},
+		{"C", "a.h", "C Header", true},
+		{"C++", "a.hpp", "C++ Header", true},
+		{"Objective-C", "a.h", "Objective-C", true},
+		{"Unknown Language", "a.xyz", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := fromEnryLanguage(tt.enryLang, tt.path, langs)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("invalid logic. enry=%v path=%v name=%v ok=%v", tt.enryLang, tt.path, name, ok)
+		}
+	}
+}
+
+func TestDetectLanguageStrategy(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/src/main.go":  "package main\n",
+		"/src/Makefile": "all:\n",
+		"/src/run":      "#!/usr/bin/env python3\nprint(1)\n",
+		"/src/README":   "hello\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := NewClocOptions()
+	opts.Fs = fs
+	langs := NewDefinedLanguages()
+
+	tests := []struct {
+		path     string
+		name     string
+		strategy string
+		ok       bool
+	}{
+		{"/src/main.go", "Go", strategyExtension, true},
+		{"/src/Makefile", "Makefile", strategyFilename, true},
+		{"/src/run", "Python", strategyShebang, true},
+		{"/src/README", "", strategyExtension, false},
+	}
+	for _, tt := range tests {
+		name, strategy, ok := detectLanguage(tt.path, langs, opts)
+		if name != tt.name || strategy != tt.strategy || ok != tt.ok {
+			t.Errorf("invalid logic. path=%v name=%v strategy=%v ok=%v", tt.path, name, strategy, ok)
+		}
+	}
+}
+
+func TestNestedCommentsCoverDefinedLanguages(t *testing.T) {
+	nested := map[string][]string{
+		"D":       {"/+"},
+		"Haskell": {"{-"},
+		"OCaml":   {"(*"},
+		"Racket":  {"#|"},
+		"Rust":    {"/*"},
+		"Swift":   {"/*"},
+	}
+	langs := NewDefinedLanguages()
+	for name, begins := range nested {
+		for _, begin := range begins {
+			if !langs.Langs[name].isNestable(begin) {
+				t.Errorf("invalid logic. language=%v begin=%v must be nestable", name, begin)
+			}
+		}
+	}
+	if langs.Langs["C"].isNestable("/*") || langs.Langs["D"].isNestable("/*") {
+		t.Errorf("invalid logic. C comments must not nest")
+	}
+}
+
+func TestDetectLanguageWithEnry(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/src/view.h": "#import <Foundation/Foundation.h>\n@interface View : NSObject\n@property NSString *name;\n@end\n",
+		"/src/noext":  "#!/usr/bin/env node\nconsole.log(1)\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := NewClocOptions()
+	opts.Fs = fs
+	opts.UseEnry = true
+	langs := NewDefinedLanguages()
+
+	if name, strategy, ok := detectLanguage("/src/view.h", langs, opts); !ok || name != "Objective-C" {
+		t.Errorf("invalid logic. name=%v strategy=%v", name, strategy)
+	}
+	if name, strategy, ok := detectLanguage("/src/noext", langs, opts); !ok || name != "JavaScript" || strategy != strategyShebang {
+		t.Errorf("invalid logic. name=%v strategy=%v", name, strategy)
+	}
+}
@@ -1,6 +1,10 @@
 package gocloc
 
-import "regexp"
+import (
+	"regexp"
+
+	"github.com/spf13/afero"
+)
 
 // ClocOptions is gocloc processor options.
 type ClocOptions struct {
@@ -12,6 +16,50 @@ type ClocOptions struct {
 	ReMatch        *regexp.Regexp
 	ReNotMatchDir  *regexp.Regexp
 	ReMatchDir     *regexp.Regexp
+	// Fs is the file system that paths are walked and read on, nil means the OS file system.
+	Fs afero.Fs
+	// NoIgnore disables .gitignore, .git/info/exclude, the global excludes file and .gocloc-ignore.
+	NoIgnore bool
+	// ExcludeDirs are the names of the directories that are not walked, see AddExcludePreset.
+	ExcludeDirs map[string]struct{}
+	// VCSDirs are the metadata directories of version control systems that are not walked,
+	// nil means DefaultVCSDirs.
+	VCSDirs []string
+	// Jobs is the number of files analyzed concurrently by Processor.Analyze.
+	// Values less than 2 analyze the files one after another, as does Debug.
+	Jobs int
+	// UseEnry classifies the files with go-enry when the extension lookup fails or is ambiguous.
+	UseEnry bool
+	// Embedded counts the code of other languages inside HTML, Markdown, Vue and Svelte files,
+	// such as <script> elements and fenced code blocks, as embedded languages.
+	Embedded bool
+	// EmbeddedLanguages are the definitions the embedded languages are looked up in,
+	// nil means NewDefinedLanguages(). Processor looks them up in its own languages.
+	EmbeddedLanguages *DefinedLanguages
+	// SkipBinary skips the files with a NUL byte.
+	SkipBinary bool
+	// SkipGenerated skips the files with a "Code generated ... DO NOT EDIT." header and lockfiles.
+	SkipGenerated bool
+	// SkipMinified skips the minified files, such as bundle.min.js.
+	SkipMinified bool
+	// SkipVendored skips the vendored files by go-enry.
+	SkipVendored bool
+	// GitRev counts the files of a commit, tag or branch of the git repository containing
+	// the paths, read from its object store instead of the work tree. See NewGitRevisionFs.
+	GitRev string
+	// CacheDir is the directory keeping the counts of the files between runs of Processor.Analyze,
+	// "" disables the cache. The files whose content and language definition did not change are
+	// not counted again. The cache is not used when a callback below is set.
+	CacheDir string
+	// ResetCache discards the cache of CacheDir before counting.
+	ResetCache bool
+	// OnCacheError is called with the error of saving the cache of CacheDir, which does not fail
+	// Processor.Analyze. The error is ignored when it is nil.
+	OnCacheError func(err error)
+
+	// The callbacks below are called in line order for each file. When Jobs is
+	// greater than 1, different files are analyzed on different goroutines, so the
+	// callbacks may run concurrently and must be safe for concurrent use.
 
 	// OnCode is triggered for each line of code.
 	OnCode func(line string)
@@ -19,14 +67,38 @@ type ClocOptions struct {
 	OnBlank func(line string)
 	// OnComment is triggered for each line of comments.
 	OnComment func(line string)
+	// onCodeText is triggered for each line of code with the text of the line without its
+	// comments, before OnCode.
+	onCodeText func(code string)
 }
 
+// DefaultVCSDirs are the metadata directories of the version control systems.
+var DefaultVCSDirs = []string{".bzr", ".cvs", ".fossil", ".git", ".hg", ".jj", ".pijul", ".svn", "_darcs"}
+
 // NewClocOptions create new ClocOptions with default values.
 func NewClocOptions() *ClocOptions {
 	return &ClocOptions{
 		Debug:          false,
 		SkipDuplicated: false,
+		Jobs:           1,
 		ExcludeExts:    make(map[string]struct{}),
 		IncludeLangs:   make(map[string]struct{}),
+		ExcludeDirs:    make(map[string]struct{}),
+	}
+}
+
+// fs returns the file system of opts.
+func (opts *ClocOptions) fs() afero.Fs {
+	if opts == nil || opts.Fs == nil {
+		return afero.NewOsFs()
+	}
+	return opts.Fs
+}
+
+// vcsDirs returns the metadata directories of the version control systems of opts.
+func (opts *ClocOptions) vcsDirs() []string {
+	if opts.VCSDirs == nil {
+		return DefaultVCSDirs
 	}
+	return opts.VCSDirs
 }
@@ -0,0 +1,68 @@
+package gocloc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ExcludePreset is a named set of directories excluded from the walk.
+type ExcludePreset struct {
+	Name        string
+	Description string
+	// Dirs are the names of the excluded directories, at any depth.
+	Dirs []string
+}
+
+// ExcludePresets are the built-in exclusion presets.
+var ExcludePresets = []ExcludePreset{
+	{
+		Name:        "vendor",
+		Description: "vendored and third-party code",
+		Dirs:        []string{"vendor", "third_party", "third-party", "external"},
+	},
+	{
+		Name:        "deps",
+		Description: "dependencies installed by package managers",
+		Dirs:        []string{"node_modules", "bower_components", "jspm_packages", ".venv", "venv", "Pods", ".bundle"},
+	},
+	{
+		Name:        "build",
+		Description: "build outputs and caches",
+		Dirs:        []string{"target", "build", "dist", "out", ".next", "__pycache__", ".gradle"},
+	},
+}
+
+// LookupExcludePreset returns the built-in exclusion preset of name.
+func LookupExcludePreset(name string) (ExcludePreset, bool) {
+	for _, preset := range ExcludePresets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return ExcludePreset{}, false
+}
+
+// AddExcludePreset excludes the directories of the preset name from the walk.
+func (opts *ClocOptions) AddExcludePreset(name string) error {
+	preset, ok := LookupExcludePreset(name)
+	if !ok {
+		return fmt.Errorf("unknown exclusion preset: %q", name)
+	}
+	if opts.ExcludeDirs == nil {
+		opts.ExcludeDirs = make(map[string]struct{})
+	}
+	for _, dir := range preset.Dirs {
+		opts.ExcludeDirs[dir] = struct{}{}
+	}
+	return nil
+}
+
+// GetFormattedPresets returns the exclusion presets, one per line.
+func GetFormattedPresets() string {
+	var buf bytes.Buffer
+	for _, preset := range ExcludePresets {
+		buf.WriteString(fmt.Sprintf("%-10v %s (%s)\n", preset.Name, preset.Description, strings.Join(preset.Dirs, ", ")))
+	}
+	return buf.String()
+}
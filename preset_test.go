@@ -0,0 +1,48 @@
+package gocloc
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddExcludePreset(t *testing.T) {
+	opts := NewClocOptions()
+	if err := opts.AddExcludePreset("deps"); err != nil {
+		t.Fatalf("invalid logic. err=[%v]", err)
+	}
+	if _, ok := opts.ExcludeDirs["node_modules"]; !ok {
+		t.Errorf("invalid logic. dirs=%v", opts.ExcludeDirs)
+	}
+	if err := opts.AddExcludePreset("unknown"); err == nil {
+		t.Errorf("invalid logic. unknown preset must be an error")
+	}
+}
+
+func TestGetAllFilesWithExcludePresets(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":                     "package main\n",
+		"vendor/lib/lib.go":           "package lib\n",
+		"web/node_modules/a/index.js": "var a = 1;\n",
+		"web/app.js":                  "var b = 2;\n",
+		"target/gen.rs":               "fn main() {}\n",
+	})
+
+	opts := NewClocOptions()
+	for _, name := range []string{"vendor", "deps", "build"} {
+		if err := opts.AddExcludePreset(name); err != nil {
+			t.Fatalf("invalid logic. err=[%v]", err)
+		}
+	}
+	files := collectFiles(t, root, opts)
+	if strings.Join(files, ",") != "main.go,web/app.js" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	// a path given explicitly is walked
+	files = collectFiles(t, filepath.Join(root, "vendor"), opts)
+	if strings.Join(files, ",") != "lib/lib.go" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
@@ -0,0 +1,102 @@
+package gocloc
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// sniffReadSize is the size of the head of a file that is sniffed.
+const sniffReadSize = 64 * 1024
+
+// Reasons of the files skipped by the content sniffing, reported in the debug output.
+const (
+	skipBinary    = "binary"
+	skipGenerated = "generated"
+	skipMinified  = "minified"
+	skipVendored  = "vendored"
+)
+
+var reGeneratedHeader = regexp.MustCompile(`(?m)^\W*(?:Code generated .* DO NOT EDIT\.?|@generated\b)`)
+
+// generatedFilenames are the files written by tools, such as lockfiles.
+var generatedFilenames = map[string]struct{}{
+	"Cargo.lock":        {},
+	"Gemfile.lock":      {},
+	"Pipfile.lock":      {},
+	"composer.lock":     {},
+	"go.sum":            {},
+	"package-lock.json": {},
+	"pnpm-lock.yaml":    {},
+	"poetry.lock":       {},
+	"yarn.lock":         {},
+}
+
+// needsSniff reports whether opts skips files by their content.
+func (opts *ClocOptions) needsSniff() bool {
+	return opts.SkipBinary || opts.SkipGenerated || opts.SkipMinified || opts.SkipVendored
+}
+
+// sniffFile returns the reason to skip path by the options of opts, or "" to count it.
+func sniffFile(fs afero.Fs, path string, opts *ClocOptions) string {
+	name := filepath.Base(path)
+	if opts.SkipVendored && enryVendored(path) {
+		return skipVendored
+	}
+	if opts.SkipGenerated {
+		if _, ok := generatedFilenames[name]; ok {
+			return skipGenerated
+		}
+	}
+	if opts.SkipMinified && strings.Contains(name, ".min.") {
+		return skipMinified
+	}
+	if !opts.SkipBinary && !opts.SkipGenerated && !opts.SkipMinified {
+		return ""
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, sniffReadSize))
+	if err != nil {
+		return ""
+	}
+
+	if opts.SkipBinary && isBinary(head) {
+		return skipBinary
+	}
+	if opts.SkipGenerated && (reGeneratedHeader.Match(head) || enryGenerated(path, head)) {
+		return skipGenerated
+	}
+	if opts.SkipMinified && isMinified(head) {
+		return skipMinified
+	}
+	return ""
+}
+
+// isBinary reports whether head has a NUL byte, as git and grep do.
+func isBinary(head []byte) bool {
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// isMinified reports whether head looks like minified code, long lines with few line breaks.
+func isMinified(head []byte) bool {
+	const maxLineLen = 500
+	const maxAverageLineLen = 150
+
+	lines := bytes.Count(head, []byte("\n")) + 1
+	longest := 0
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		if longest < len(line) {
+			longest = len(line)
+		}
+	}
+	return longest > maxLineLen && len(head)/lines > maxAverageLineLen
+}
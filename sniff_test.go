@@ -0,0 +1,87 @@
+package gocloc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMinified(t *testing.T) {
+	minified := "var a=1;" + strings.Repeat("function f(){return a+1};", 100) + "\n"
+	if !isMinified([]byte(minified)) {
+		t.Errorf("invalid logic. minified code is not detected")
+	}
+
+	source := strings.Repeat("function f() {\n  return 1;\n}\n", 100)
+	if isMinified([]byte(source)) {
+		t.Errorf("invalid logic. source code is detected as minified")
+	}
+
+	// one long line in a normal file
+	source += strings.Repeat("x", 1000) + "\n"
+	if isMinified([]byte(source)) {
+		t.Errorf("invalid logic. one long line is detected as minified")
+	}
+}
+
+func TestGetAllFilesWithSniffing(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":           "package main\n",
+		"api.pb.go":         "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"gen.py":            "# @generated by a tool\nx = 1\n",
+		"package-lock.json": "{}\n",
+		"data.c":            "int a;\x00\x01\x02\n",
+		"app.min.js":        "var a = 1;\n",
+		"bundle.js":         strings.Repeat("var b=2;", 200) + "\n",
+		"util.js":           "var c = 3;\n",
+	})
+
+	opts := NewClocOptions()
+	files := collectFiles(t, root, opts)
+	if len(files) != 8 {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	opts.SkipBinary = true
+	files = collectFiles(t, root, opts)
+	if strings.Contains(strings.Join(files, ","), "data.c") || len(files) != 7 {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	opts.SkipGenerated = true
+	files = collectFiles(t, root, opts)
+	if strings.Join(files, ",") != "app.min.js,bundle.js,main.go,util.js" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	opts.SkipMinified = true
+	files = collectFiles(t, root, opts)
+	if strings.Join(files, ",") != "main.go,util.js" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
+
+func TestEnryGenerated(t *testing.T) {
+	tests := []struct {
+		path, content string
+		generated     bool
+	}{
+		{"vendor/Cargo.lock", "[[package]]\n", true},
+		{"api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n", true},
+		{"app.js", "var a = 1;\n//# sourceMappingURL=app.js.map\n", true},
+		{"app.js.map", `{"version":3,"sources":["app.ts"]}` + "\n", true},
+		{"api_pb2.py", "# Generated by the protocol buffer compiler.  DO NOT EDIT!\n# source: api.proto\n", true},
+		{"lib/model.g.dart", "// GENERATED CODE - DO NOT MODIFY BY HAND\n", true},
+		// the markers count only in the lines and the files they are expected in
+		{"doc.go", strings.Repeat("//\n", 40) + "// Code generated by hand.\n", false},
+		{"api.js", "// Generated by the protocol buffer compiler.  DO NOT EDIT!\n", false},
+		// minified files are the category of SkipMinified
+		{"bundle.js", strings.Repeat("var b=2;", 200) + "\n", false},
+		{"main.go", "package main\n", false},
+	}
+	for _, tt := range tests {
+		if got := enryGenerated(tt.path, []byte(tt.content)); got != tt.generated {
+			t.Errorf("invalid logic. path=%v generated=%v", tt.path, got)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package gocloc
 
 import (
-	"crypto/md5"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/afero"
 )
 
 func trimBOM(line string) string {
@@ -22,7 +27,7 @@ func trimBOM(line string) string {
 func containsComment(line string, multiLines [][]string) bool {
 	for _, mlcomm := range multiLines {
 		for _, comm := range mlcomm {
-			if strings.Contains(line, comm) {
+			if comm != "" && strings.Contains(line, comm) {
 				return true
 			}
 		}
@@ -30,49 +35,156 @@ func containsComment(line string, multiLines [][]string) bool {
 	return false
 }
 
-func nextRune(s string) rune {
-	for _, r := range s {
-		return r
+// containsMultiLineString reports whether line may start a string literal continuing on the next lines.
+func containsMultiLineString(line string, literals []StringLiteral) bool {
+	for _, sl := range literals {
+		if sl.MultiLine && strings.Contains(line, sl.Begin) {
+			return true
+		}
 	}
-	return 0
+	return false
 }
 
-func checkMD5Sum(path string, fileCache map[string]struct{}) (ignore bool) {
-	content, err := os.ReadFile(path)
-	if err != nil {
+// needsScan reports whether line has to be scanned for comment markers and string literals.
+func needsScan(line string, language *Language) bool {
+	if containsComment(line, language.multiLines) || containsMultiLineString(line, language.stringLiterals) {
 		return true
 	}
+	if !language.lineStartComments {
+		for _, comm := range language.lineComments {
+			if strings.Contains(line, comm) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	// calc md5sum
-	hash := md5.Sum(content)
-	c := fmt.Sprintf("%x", hash)
-	if _, ok := fileCache[c]; ok {
+// matchTrailingComment reports whether a line comment after code begins at pos of line.
+// With guessed string literals, the comment needs a space before it, so that $# and a#b
+// in a shell are code. Comment markers made of letters, such as REM, are whole words.
+func matchTrailingComment(line string, pos int, language *Language) bool {
+	if language.lineStartComments {
+		return false
+	}
+	for _, comm := range language.lineComments {
+		if comm == "" || !strings.HasPrefix(line[pos:], comm) {
+			continue
+		}
+		if language.guessedLiterals && pos > 0 && !unicode.IsSpace(rune(line[pos-1])) {
+			continue
+		}
+		if isWordByte(comm[0]) && pos > 0 && isWordByte(line[pos-1]) {
+			continue
+		}
+		if end := pos + len(comm); isWordByte(comm[len(comm)-1]) && end < len(line) && isWordByte(line[end]) {
+			continue
+		}
 		return true
 	}
-
-	fileCache[c] = struct{}{}
 	return false
 }
 
-func isVCSDir(path string) bool {
-	if len(path) > 1 && path[0] == os.PathSeparator {
-		path = path[1:]
+// isWordByte reports whether b is an ASCII letter, digit or underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// matchMultiLineBegin returns the multi-line comment pair that line begins with.
+func matchMultiLineBegin(line string, multiLines [][]string) (begin, end string, ok bool) {
+	for _, ml := range multiLines {
+		if ml[0] != "" && strings.HasPrefix(line, ml[0]) {
+			return ml[0], ml[1], true
+		}
+	}
+	return "", "", false
+}
+
+// matchStringLiteral returns the string literal that line begins with.
+func matchStringLiteral(line string, literals []StringLiteral) *StringLiteral {
+	for i := range literals {
+		sl := &literals[i]
+		if !strings.HasPrefix(line, sl.Begin) {
+			continue
+		}
+		rest := line[len(sl.Begin):]
+		if sl.Char && !isCharLiteral(rest, sl) || sl.closed && !closesOnLine(rest, sl) {
+			continue
+		}
+		return sl
 	}
-	vcsDirs := []string{".bzr", ".cvs", ".hg", ".git", ".svn"}
-	for _, dir := range vcsDirs {
-		if strings.Contains(path, dir) {
+	return nil
+}
+
+// closesOnLine reports whether s, the text after the begin marker of sl, has the end marker of sl.
+func closesOnLine(s string, sl *StringLiteral) bool {
+	for pos := 0; pos < len(s); {
+		switch {
+		case sl.Escape != "" && strings.HasPrefix(s[pos:], sl.Escape):
+			pos += len(sl.Escape) + 1
+		case strings.HasPrefix(s[pos:], sl.End):
 			return true
+		default:
+			pos++
 		}
 	}
 	return false
 }
 
-func checkDefaultIgnore(path string, info os.FileInfo, isVCS bool) bool {
+// maxCharEscapeLen is the length of the longest escape sequence of a char literal, such as \u{10FFFF}.
+const maxCharEscapeLen = 10
+
+// isCharLiteral reports whether s, the text after the begin marker of the char literal sl,
+// holds one character or one escape sequence followed by the end marker.
+func isCharLiteral(s string, sl *StringLiteral) bool {
+	if sl.Escape != "" && strings.HasPrefix(s, sl.Escape) {
+		rest := s[len(sl.Escape):]
+		if rest == "" {
+			return false
+		}
+		// the escaped character may be the end marker itself, as in '\''
+		_, size := utf8.DecodeRuneInString(rest)
+		i := strings.Index(rest[size:], sl.End)
+		return i >= 0 && size+i <= maxCharEscapeLen
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || r == utf8.RuneError || strings.HasPrefix(s, sl.End) {
+		return false
+	}
+	return strings.HasPrefix(s[size:], sl.End)
+}
+
+func nextRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+// isVCSDir reports whether a component of path is one of vcsDirs.
+func isVCSDir(path string, vcsDirs []string) bool {
+	for _, component := range strings.Split(filepath.ToSlash(path), "/") {
+		for _, dir := range vcsDirs {
+			if component == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileEntry is the part of os.FileInfo and fs.DirEntry used to filter the walked paths.
+type fileEntry interface {
+	Name() string
+	IsDir() bool
+}
+
+func checkDefaultIgnore(path string, info fileEntry, isVCS bool, vcsDirs []string) bool {
 	if info.IsDir() {
 		// directory is ignore
 		return true
 	}
-	if !isVCS && isVCSDir(path) {
+	if !isVCS && isVCSDir(path, vcsDirs) {
 		// vcs file or directory is ignore
 		return true
 	}
@@ -80,7 +192,7 @@ func checkDefaultIgnore(path string, info os.FileInfo, isVCS bool) bool {
 	return false
 }
 
-func checkOptionMatch(path string, info os.FileInfo, opts *ClocOptions) bool {
+func checkOptionMatch(path string, info fileEntry, opts *ClocOptions) bool {
 	// check match directory & file options
 	if opts.ReNotMatch != nil && opts.ReNotMatch.MatchString(info.Name()) {
 		return false
@@ -101,19 +213,101 @@ func checkOptionMatch(path string, info os.FileInfo, opts *ClocOptions) bool {
 	return true
 }
 
+// walkDir walks root like filepath.WalkDir. The OS file system is walked by filepath.WalkDir
+// without a Lstat call for each file, the other file systems by afero.Walk.
+func walkDir(fsys afero.Fs, root string, fn fs.WalkDirFunc) error {
+	if _, ok := fsys.(*afero.OsFs); ok {
+		return filepath.WalkDir(root, fn)
+	}
+	return afero.Walk(fsys, root, func(path string, info os.FileInfo, err error) error {
+		var d fs.DirEntry
+		if info != nil {
+			d = fs.FileInfoToDirEntry(info)
+		}
+		return fn(path, d, err)
+	})
+}
+
+// pruneDir reports whether the walk skips the directory path, with the reason for the debug output.
+func pruneDir(path string, vcsInRoot bool, opts *ClocOptions) (string, bool) {
+	if !vcsInRoot && isVCSDir(path, opts.vcsDirs()) {
+		return "vcs directory", true
+	}
+	if opts.ReNotMatchDir != nil && opts.ReNotMatchDir.MatchString(path) {
+		return "match --not-match-d", true
+	}
+	return "", false
+}
+
 // getAllFiles return all of the files to be analyzed in paths.
-func getAllFiles(paths []string, languages *DefinedLanguages, opts *ClocOptions) (result map[string]*Language, err error) {
+// If fn is not nil, it is called for each file as soon as the file is appended to the Files of its language.
+// The walk stops with ctx.Err() when ctx is done.
+func getAllFiles(ctx context.Context, paths []string, languages *DefinedLanguages, opts *ClocOptions, fn func(path string, language *Language)) (result map[string]*Language, err error) {
+	return walkFiles(ctx, paths, languages, opts, fn, nil)
+}
+
+// walkFiles is like getAllFiles. If onDuplicate is not nil, it is called for each file skipped
+// as a duplicate of the file original.
+func walkFiles(ctx context.Context, paths []string, languages *DefinedLanguages, opts *ClocOptions, fn func(path string, language *Language), onDuplicate func(path, original string)) (result map[string]*Language, err error) {
 	result = make(map[string]*Language, 0)
-	fileCache := make(map[string]struct{})
+	fsys := opts.fs()
+	var dedup *duplicateDetector
+	if !opts.SkipDuplicated {
+		dedup = newDuplicateDetector(fsys, func(path, original string) {
+			if opts.Debug {
+				fmt.Printf("[ignore=%v] duplicate of %v\n", path, original)
+			}
+			if onDuplicate != nil {
+				onDuplicate(path, original)
+			}
+		})
+		// the files waiting for the detector are appended before returning
+		defer dedup.wait()
+	}
 
 	for _, root := range paths {
-		vcsInRoot := isVCSDir(root)
-		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		vcsInRoot := isVCSDir(root, opts.vcsDirs())
+		var ignorer *ignoreMatcher
+		if !opts.NoIgnore {
+			ignorer = newIgnoreMatcher(fsys, root)
+		}
+		err = walkDir(fsys, root, func(path string, info fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "%s\n", err)
 				return nil
 			}
-			if ignore := checkDefaultIgnore(path, info, vcsInRoot); ignore {
+			if ignorer != nil {
+				if ignorer.ignored(path, info.IsDir()) {
+					if opts.Debug {
+						fmt.Printf("[ignore=%v] match ignore file\n", path)
+					}
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					ignorer.loadDir(path)
+				}
+			}
+			if info.IsDir() && path != root {
+				if _, ok := opts.ExcludeDirs[info.Name()]; ok {
+					if opts.Debug {
+						fmt.Printf("[ignore=%v] excluded directory\n", path)
+					}
+					return filepath.SkipDir
+				}
+				if reason, ok := pruneDir(path, vcsInRoot, opts); ok {
+					if opts.Debug {
+						fmt.Printf("[ignore=%v] %s\n", path, reason)
+					}
+					return filepath.SkipDir
+				}
+			}
+			if ignore := checkDefaultIgnore(path, info, vcsInRoot, opts.vcsDirs()); ignore {
 				return nil
 			}
 
@@ -122,40 +316,51 @@ func getAllFiles(paths []string, languages *DefinedLanguages, opts *ClocOptions)
 				return nil
 			}
 
-			if ext, ok := getFileType(path, opts); ok {
-				if targetExt, ok := Exts[ext]; ok {
-					// check exclude extension
-					if _, ok := opts.ExcludeExts[targetExt]; ok {
-						return nil
-					}
+			if targetExt, strategy, ok := detectLanguage(path, languages, opts); ok {
+				if opts.Debug {
+					fmt.Printf("[detect=%v] lang=%v strategy=%v\n", path, targetExt, strategy)
+				}
 
-					if len(opts.IncludeLangs) != 0 {
-						if _, ok = opts.IncludeLangs[targetExt]; !ok {
-							return nil
-						}
+				// check exclude extension
+				if _, ok := opts.ExcludeExts[targetExt]; ok {
+					return nil
+				}
+
+				if len(opts.IncludeLangs) != 0 {
+					if _, ok = opts.IncludeLangs[targetExt]; !ok {
+						return nil
 					}
+				}
 
-					if !opts.SkipDuplicated {
-						ignore := checkMD5Sum(path, fileCache)
-						if ignore {
-							if opts.Debug {
-								fmt.Printf("[ignore=%v] find same md5\n", path)
-							}
-							return nil
+				if opts.needsSniff() {
+					if reason := sniffFile(fsys, path, opts); reason != "" {
+						if opts.Debug {
+							fmt.Printf("[ignore=%v] %s file\n", path, reason)
 						}
+						return nil
 					}
+				}
 
+				accept := func() {
 					if _, ok := result[targetExt]; !ok {
-						result[targetExt] = NewLanguage(
-							languages.Langs[targetExt].Name,
-							languages.Langs[targetExt].lineComments,
-							languages.Langs[targetExt].multiLines)
+						result[targetExt] = languages.Langs[targetExt].emptyCopy()
 					}
 					result[targetExt].Files = append(result[targetExt].Files, path)
+					if fn != nil {
+						fn(path, result[targetExt])
+					}
+				}
+				if dedup != nil {
+					dedup.add(path, accept)
+				} else {
+					accept()
 				}
 			}
 			return nil
 		})
+		if err != nil {
+			return
+		}
 	}
 	return
 }
@@ -1,8 +1,12 @@
 package gocloc
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,17 +25,6 @@ func TestContainsComment(t *testing.T) {
 	}
 }
 
-func TestCheckMD5SumIgnore(t *testing.T) {
-	fileCache := make(map[string]struct{})
-
-	if checkMD5Sum("./utils_test.go", fileCache) {
-		t.Errorf("invalid sequence")
-	}
-	if !checkMD5Sum("./utils_test.go", fileCache) {
-		t.Errorf("invalid sequence")
-	}
-}
-
 func TestCheckDefaultIgnore(t *testing.T) {
 	appFS := afero.NewMemMapFs()
 	if err := appFS.Mkdir("/test", os.ModeDir); err != nil {
@@ -40,16 +33,16 @@ func TestCheckDefaultIgnore(t *testing.T) {
 	_, _ = appFS.Create("/test/one.go")
 
 	fileInfo, _ := appFS.Stat("/")
-	if !checkDefaultIgnore("/", fileInfo, false) {
+	if !checkDefaultIgnore("/", fileInfo, false, DefaultVCSDirs) {
 		t.Errorf("invalid logic: this is directory")
 	}
 
-	if !checkDefaultIgnore("/", fileInfo, true) {
+	if !checkDefaultIgnore("/", fileInfo, true, DefaultVCSDirs) {
 		t.Errorf("invalid logic: this is vcs file or directory")
 	}
 
 	fileInfo, _ = appFS.Stat("/test/one.go")
-	if checkDefaultIgnore("/test/one.go", fileInfo, false) {
+	if checkDefaultIgnore("/test/one.go", fileInfo, false, DefaultVCSDirs) {
 		t.Errorf("invalid logic: should not ignore this file")
 	}
 }
@@ -106,3 +99,134 @@ func TestCheckOptionMatch(t *testing.T) {
 		t.Errorf("invalid logic: renotmatchdir is not ignore")
 	}
 }
+
+func BenchmarkGetAllFilesWithExcludedDirs(b *testing.B) {
+	benchmarkGetAllFiles(b, true)
+}
+
+// BenchmarkGetAllFilesWithoutExcludedDirs walks the tree of BenchmarkGetAllFilesWithExcludedDirs
+// into node_modules and .git, the baseline of the pruning.
+func BenchmarkGetAllFilesWithoutExcludedDirs(b *testing.B) {
+	benchmarkGetAllFiles(b, false)
+}
+
+func benchmarkGetAllFiles(b *testing.B, exclude bool) {
+	root := b.TempDir()
+	for i := 0; i < 20; i++ {
+		writeBenchmarkFile(b, filepath.Join(root, "src", fmt.Sprintf("file%d.go", i)))
+	}
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 20; j++ {
+			writeBenchmarkFile(b, filepath.Join(root, "node_modules", fmt.Sprintf("pkg%d", i), fmt.Sprintf("index%d.js", j)))
+			writeBenchmarkFile(b, filepath.Join(root, ".git", "objects", fmt.Sprintf("%02x", i), fmt.Sprintf("object%d", j)))
+		}
+	}
+
+	opts := NewClocOptions()
+	opts.NoIgnore = true
+	opts.SkipDuplicated = true
+	if exclude {
+		opts.ReNotMatchDir = regexp.MustCompile(`node_modules`)
+	} else {
+		opts.VCSDirs = []string{}
+	}
+	languages := NewDefinedLanguages()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := getAllFiles(context.Background(), []string{root}, languages, opts, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n := len(result["Go"].Files); n != 20 {
+			b.Fatalf("invalid logic. files=%v", n)
+		}
+	}
+}
+
+func writeBenchmarkFile(b *testing.B, path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(path), 0o644); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestGetAllFilesPrunesDirs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":           "package main\n",
+		"gen/a.go":          "package gen\n",
+		"gen/sub/b.go":      "package sub\n",
+		".git/hooks/pre.py": "x = 1\n",
+		"src/c.go":          "package src\n",
+	})
+
+	opts := NewClocOptions()
+	opts.NoIgnore = true
+	opts.ReNotMatchDir = regexp.MustCompile(`gen$`)
+	files := collectFiles(t, root, opts)
+	if strings.Join(files, ",") != "main.go,src/c.go" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
+
+func TestIsVCSDir(t *testing.T) {
+	tests := []struct {
+		path string
+		vcs  bool
+	}{
+		{".git", true},
+		{"/repo/.git", true},
+		{"repo/.git/objects/ab", true},
+		{"repo/.hg/store", true},
+		{"repo/.svn", true},
+		{"repo/.jj/repo", true},
+		{"repo/.pijul", true},
+		{"repo/_darcs/patches", true},
+		{"repo/.fossil", true},
+		{"src/.github/workflows", false},
+		{"src/.github/workflows/ci.yml", false},
+		{"my.git-helpers/run.sh", false},
+		{"docs/.hgignore-notes", false},
+		{"repo/.gitignore", false},
+		{"repo/.gitattributes", false},
+		{"repo/.svnkit/lib", false},
+		{"repo/my_darcs", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if vcs := isVCSDir(filepath.FromSlash(tt.path), DefaultVCSDirs); vcs != tt.vcs {
+			t.Errorf("invalid logic. path=%v vcs=%v", tt.path, vcs)
+		}
+	}
+
+	// configured list
+	if !isVCSDir("repo/.custom/x", []string{".custom"}) || isVCSDir("repo/.git/x", []string{".custom"}) {
+		t.Errorf("invalid logic. custom vcs dirs are not used")
+	}
+}
+
+func TestGetAllFilesWithVCSLikeNames(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".github/workflows/ci.yml": "on: push\n",
+		"my.git-helpers/run.sh":    "echo run\n",
+		".jj/repo/store.py":        "x = 1\n",
+		"main.go":                  "package main\n",
+	})
+
+	opts := NewClocOptions()
+	opts.NoIgnore = true
+	files := collectFiles(t, root, opts)
+	if strings.Join(files, ",") != ".github/workflows/ci.yml,main.go,my.git-helpers/run.sh" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+
+	opts.VCSDirs = []string{".github"}
+	files = collectFiles(t, root, opts)
+	if strings.Join(files, ",") != ".jj/repo/store.py,main.go,my.git-helpers/run.sh" {
+		t.Errorf("invalid logic. files=%v", files)
+	}
+}
@@ -17,10 +17,12 @@ const (
 
 // XMLTotalLanguages is the total result in XML format.
 type XMLTotalLanguages struct {
-	SumFiles int32 `xml:"sum_files,attr"`
-	Code     int32 `xml:"code,attr"`
-	Comment  int32 `xml:"comment,attr"`
-	Blank    int32 `xml:"blank,attr"`
+	SumFiles   int32 `xml:"sum_files,attr"`
+	Code       int32 `xml:"code,attr"`
+	Comment    int32 `xml:"comment,attr"`
+	DocComment int32 `xml:"doc_comment,attr,omitempty"`
+	Blank      int32 `xml:"blank,attr"`
+	Mixed      int32 `xml:"mixed,attr,omitempty"`
 }
 
 // XMLResultLanguages stores the results in XML format.
@@ -31,9 +33,11 @@ type XMLResultLanguages struct {
 
 // XMLTotalFiles is the total result per file in XML format.
 type XMLTotalFiles struct {
-	Code    int32 `xml:"code,attr"`
-	Comment int32 `xml:"comment,attr"`
-	Blank   int32 `xml:"blank,attr"`
+	Code       int32 `xml:"code,attr"`
+	Comment    int32 `xml:"comment,attr"`
+	DocComment int32 `xml:"doc_comment,attr,omitempty"`
+	Blank      int32 `xml:"blank,attr"`
+	Mixed      int32 `xml:"mixed,attr,omitempty"`
 }
 
 // XMLResultFiles stores per file results in XML format.
@@ -47,6 +51,14 @@ type XMLResult struct {
 	XMLName      xml.Name            `xml:"results"`
 	XMLFiles     *XMLResultFiles     `xml:"files,omitempty"`
 	XMLLanguages *XMLResultLanguages `xml:"languages,omitempty"`
+	XMLDiff      *XMLResultDiff      `xml:"diff,omitempty"`
+}
+
+// XMLResultDiff stores the results of Processor.Diff in XML format.
+type XMLResultDiff struct {
+	Files     []ClocDiffFile     `xml:"file,omitempty"`
+	Languages []ClocDiffLanguage `xml:"language,omitempty"`
+	Total     ClocDiffLanguage   `xml:"total"`
 }
 
 // Encode outputs XMLResult in a human readable format.
@@ -62,19 +74,24 @@ func NewXMLResultFromCloc(total *Language, sortedLanguages Languages, option XML
 	var langs []ClocLanguage
 	for _, language := range sortedLanguages {
 		c := ClocLanguage{
-			Name:       language.Name,
-			FilesCount: int32(len(language.Files)),
-			Code:       language.Code,
-			Comments:   language.Comments,
-			Blanks:     language.Blanks,
+			Name:        language.Name,
+			FilesCount:  int32(len(language.Files)),
+			Code:        language.Code,
+			Comments:    language.Comments,
+			DocComments: language.DocComments,
+			Blanks:      language.Blanks,
+			Mixed:       language.Mixed,
+			Embedded:    embeddedClocLanguages(&language),
 		}
 		langs = append(langs, c)
 	}
 	t := XMLTotalLanguages{
-		Code:     total.Code,
-		Comment:  total.Comments,
-		Blank:    total.Blanks,
-		SumFiles: total.Total,
+		Code:       total.Code,
+		Comment:    total.Comments,
+		DocComment: total.DocComments,
+		Blank:      total.Blanks,
+		Mixed:      total.Mixed,
+		SumFiles:   total.Total,
 	}
 	f := &XMLResultLanguages{
 		Languages: langs,
@@ -85,3 +102,39 @@ func NewXMLResultFromCloc(total *Language, sortedLanguages Languages, option XML
 		XMLLanguages: f,
 	}
 }
+
+// NewXMLFilesResultFromCloc returns XMLResult with the data set of each file.
+func NewXMLFilesResultFromCloc(total *Language, sortedFiles ClocFiles) *XMLResult {
+	t := XMLTotalFiles{
+		Code:       total.Code,
+		Comment:    total.Comments,
+		DocComment: total.DocComments,
+		Blank:      total.Blanks,
+		Mixed:      total.Mixed,
+	}
+	f := &XMLResultFiles{
+		Files: sortedFiles,
+		Total: t,
+	}
+
+	return &XMLResult{
+		XMLFiles: f,
+	}
+}
+
+// NewXMLDiffResult returns XMLResult with the languages, or the files with option
+// XMLResultWithFiles, of a DiffResult.
+func NewXMLDiffResult(result *DiffResult, option XMLResultType) *XMLResult {
+	t := *result.Total
+	t.Name = ""
+	d := &XMLResultDiff{Total: t}
+	if option == XMLResultWithFiles {
+		d.Files = result.SortedDiffFiles()
+	} else {
+		d.Languages = result.SortedDiffLanguages()
+	}
+
+	return &XMLResult{
+		XMLDiff: d,
+	}
+}
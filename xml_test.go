@@ -0,0 +1,32 @@
+package gocloc
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestOutputXMLFiles(t *testing.T) {
+	total := &Language{Code: 3, Comments: 2, Blanks: 1}
+	files := []ClocFile{
+		{Name: "one.go", Lang: "Go", Code: 2, Comments: 2, Blanks: 1},
+		{Name: "two.go", Lang: "Go", Code: 1},
+	}
+	xmlResult := NewXMLFilesResultFromCloc(total, files)
+	if xmlResult.XMLLanguages != nil {
+		t.Errorf("invalid result. languages must be empty")
+	}
+	if len(xmlResult.XMLFiles.Files) != 2 || xmlResult.XMLFiles.Files[1].Name != "two.go" {
+		t.Errorf("invalid result. files=%v", xmlResult.XMLFiles.Files)
+	}
+
+	buf, err := xml.Marshal(xmlResult)
+	if err != nil {
+		t.Fatalf("xml marshal error. err=[%v]", err)
+	}
+
+	actualXMLText := `<results><files><file code="2" comment="2" blank="1" name="one.go" language="Go"></file><file code="1" comment="0" blank="0" name="two.go" language="Go"></file><total code="3" comment="2" blank="1"></total></files></results>`
+	resultXMLText := string(buf)
+	if actualXMLText != resultXMLText {
+		t.Errorf("invalid result. '%s'", resultXMLText)
+	}
+}